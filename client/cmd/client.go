@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -25,6 +26,7 @@ var playCmd = &cobra.Command{
 		- if the query is a playlist, it will play all the songs in the playlist
 		- if the query is a audio file, it will play the audio file
 		- if the query is a youtube link, it will play the audio from the link
+		- if the query is a radio stream or a .pls/.m3u radio playlist, it will play it live
 		- if the query is a search query, it will search and return the results to select from
 	`,
 	ValidArgsFunction: func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
@@ -54,6 +56,24 @@ var playCmd = &cobra.Command{
 	},
 }
 
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "search a song <query>",
+	Long: `search a song <query>
+	search is like play, but the query is always searched, it is never detected as a url, file or playlist
+	the results are ranked, the library matches words by prefix and tolerates typos
+		- use --local to search only the library (cached songs), no network needed
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Println("no query specified")
+			return
+		}
+		local, _ := cmd.Flags().GetBool("local")
+		views.SearchOnlyThenSelect(strings.Join(args, " "), local, client)
+	},
+}
+
 var pauseCmd = &cobra.Command{
 	Use:   "pause",
 	Short: "pause the current song",
@@ -366,6 +386,7 @@ var playlistAddCmd = &cobra.Command{
 	Short: "add music(s) to a playlist",
 	Long: `add music(s) to a playlist
 this command is similar to the "play" command, but it will add the music to the playlist instead of adding it to the queue
+use --at <index> to insert the music at a position instead of appending it
 you can check the "list <playlist>" command to see the songs in the playlist
 and you can play it using the "list play" command
 `,
@@ -388,14 +409,273 @@ and you can play it using the "list play" command
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	},
-	Run: func(_ *cobra.Command, args []string) {
+	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 2 {
 			fmt.Println("playlist name and query required")
 			return
 		}
 		listname := strings.TrimSpace(args[0])
 		query := strings.Join(args[1:], " ")
-		views.SearchThenAddToPlayList(listname, query, client)
+		position, _ := cmd.Flags().GetInt("at")
+		views.SearchThenAddToPlayList(listname, query, position, client)
+	},
+}
+
+var playlistRenameCmd = &cobra.Command{
+	Use:   "rename <playlist> <new name>",
+	Short: "rename a playlist",
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) == 0 {
+			return controller.GetPlayListsNames(client), cobra.ShellCompDirectiveDefault
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println("playlist name and new name required")
+			return
+		}
+		controller.RenamePlayList(
+			strings.TrimSpace(args[0]),
+			strings.TrimSpace(args[1]),
+			client,
+		)
+	},
+}
+
+var playlistCopyCmd = &cobra.Command{
+	Use:   "copy <playlist> <new playlist>",
+	Short: "duplicate a playlist",
+	Long: `duplicate a playlist
+the new playlist gets the songs and the description of the copied one
+`,
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) == 0 {
+			return controller.GetPlayListsNames(client), cobra.ShellCompDirectiveDefault
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println("playlist name and new playlist name required")
+			return
+		}
+		controller.CopyPlayList(
+			strings.TrimSpace(args[0]),
+			strings.TrimSpace(args[1]),
+			client,
+		)
+	},
+}
+
+var playlistMergeCmd = &cobra.Command{
+	Use:   "merge <playlist> <playlist>... --into <playlist>",
+	Short: "merge playlists",
+	Long: `merge playlists
+the songs of the playlists are appended in order to the --into playlist, songs already there are skipped
+the --into playlist is created if it doesn't exist, by default the songs are merged into the first playlist
+`,
+	ValidArgsFunction: func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return controller.GetPlayListsNames(client), cobra.ShellCompDirectiveDefault
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Println("at least two playlists required")
+			return
+		}
+		var names []string
+		for _, arg := range args {
+			names = append(names, strings.TrimSpace(arg))
+		}
+		into, _ := cmd.Flags().GetString("into")
+		into = strings.TrimSpace(into)
+		if into == "" {
+			into = names[0]
+		}
+		controller.MergePlayLists(names, into, client)
+		views.PlayListMusicsDisplay(into, client)
+	},
+}
+
+var playlistDescribeCmd = &cobra.Command{
+	Use:   "describe <playlist> <description>",
+	Short: "set the description of a playlist",
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) == 0 {
+			return controller.GetPlayListsNames(client), cobra.ShellCompDirectiveDefault
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("playlist name required")
+			return
+		}
+		controller.DescribePlayList(
+			strings.TrimSpace(args[0]),
+			strings.TrimSpace(strings.Join(args[1:], " ")),
+			client,
+		)
+	},
+}
+
+var playlistImportCmd = &cobra.Command{
+	Use:   "import <file> [playlist]",
+	Short: "import a playlist file",
+	Long: `import a playlist file (.m3u, .m3u8, .xspf, .jspf)
+the songs are appended to the playlist, which is created if it doesn't exist
+by default the playlist is named after the file
+local paths are read from the disk (relative ones from the playlist file directory), urls are downloaded
+the entries without a usable location are matched by title and artist in the library, then searched online
+the entries that couldn't be added are reported
+`,
+	Args: cobra.RangeArgs(1, 2),
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		path := strings.TrimSpace(args[0])
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if len(args) == 2 {
+			name = strings.TrimSpace(args[1])
+		}
+		views.PlayListImportDisplay(path, name, client)
+	},
+}
+
+var playlistExportCmd = &cobra.Command{
+	Use:   "export <playlist> <file>",
+	Short: "export a playlist to a file",
+	Long: `export a playlist to a file (.m3u, .m3u8, .xspf, .jspf)
+the songs are written with their source (url or local path)
+use --files to also extract the songs from the cache, out.m3u8 gets its songs in the out directory
+`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) == 0 {
+			return controller.GetPlayListsNames(client), cobra.ShellCompDirectiveDefault
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		files, _ := cmd.Flags().GetBool("files")
+		count := controller.ExportPlayList(
+			strings.TrimSpace(args[0]),
+			args[1],
+			files,
+			client,
+		)
+		fmt.Printf("💾 exported %d songs to %s\n", count, args[1])
+	},
+}
+
+var playlistSmartCmd = &cobra.Command{
+	Use:   "smart",
+	Short: "list smart playlists",
+	Long: `list smart playlists
+a smart playlist is the songs of the library matching a rule, the rule is evaluated every time the playlist is played
+play and show them like the other playlists: "list play <name>", "list <name>"
+`,
+	Run: func(_ *cobra.Command, _ []string) {
+		views.SmartPlayListsDisplay(client)
+	},
+}
+
+var playlistSmartCreateCmd = &cobra.Command{
+	Use:   "create <name> <rule>",
+	Short: "create a smart playlist",
+	Long: `create a smart playlist from a rule
+conditions are joined with AND, OR, NOT and parentheses, text values can be quoted
+  - text fields: name, title, artist, album, source (=, !=, contains or ~)
+  - number fields: year, track, duration (seconds), size (bytes), play_count (=, !=, <, <=, >, >=)
+  - date fields: added, played ("in last <n> <minutes|hours|days|weeks|months|years>" or compared to 2006-01-02)
+  - pinned = true|false
+examples:
+  retro list smart create popular 'source = youtube AND play_count > 5'
+  retro list smart create fresh 'added in last 30 days'
+  retro list smart create daft 'artist = "Daft Punk" OR album contains discovery'
+`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(_ *cobra.Command, args []string) {
+		controller.CreateSmartPlayList(
+			strings.TrimSpace(args[0]),
+			strings.Join(args[1:], " "),
+			client,
+		)
+		views.PlayListMusicsDisplay(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var playlistSmartRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "remove a smart playlist",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil || len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, playlist := range controller.GetSmartPlayLists(client) {
+			names = append(names, playlist.Name)
+		}
+		return names, cobra.ShellCompDirectiveDefault
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		controller.RemoveSmartPlayList(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var playlistMoveCmd = &cobra.Command{
+	Use:   "move <playlist> <from index> <to index>",
+	Short: "move a song inside a playlist",
+	Long: `move a song inside a playlist
+the indexes are the ones shown by the "list <playlist>" command
+`,
+	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) == 0 {
+			return controller.GetPlayListsNames(client), cobra.ShellCompDirectiveDefault
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		if len(args) != 3 {
+			fmt.Println("playlist name, from index and to index required")
+			return
+		}
+		listname := strings.TrimSpace(args[0])
+		from, err := strconv.Atoi(strings.TrimSpace(args[1]))
+		if err != nil {
+			fmt.Println("Invalid from index")
+			return
+		}
+		to, err := strconv.Atoi(strings.TrimSpace(args[2]))
+		if err != nil {
+			fmt.Println("Invalid to index")
+			return
+		}
+		controller.MoveMusicInPlayList(listname, from, to, client)
+		views.PlayListMusicsDisplay(listname, client)
 	},
 }
 
@@ -530,12 +810,429 @@ var cleanCacheCmd = &cobra.Command{
 	Short: "clean the cache",
 	Long: `clean the cache
   this command will clean the cache from the server
+  songs in a playlist and pinned songs are kept
   `,
 	Run: func(_ *cobra.Command, _ []string) {
 		controller.CleanCache(client)
 	},
 }
 
+// libraryArgs reads the filter, sort and paging flags of the library commands
+func libraryArgs(cmd *cobra.Command) shared.LibraryArgs {
+	flags := cmd.Flags()
+	artist, _ := flags.GetString("artist")
+	album, _ := flags.GetString("album")
+	source, _ := flags.GetString("source")
+	sort, _ := flags.GetString("sort")
+	desc, _ := flags.GetBool("desc")
+	page, _ := flags.GetInt("page")
+	limit, _ := flags.GetInt("limit")
+	if page < 1 {
+		page = 1
+	}
+	return shared.LibraryArgs{
+		Artist: artist,
+		Album:  album,
+		Source: source,
+		Sort:   sort,
+		Desc:   desc,
+		Offset: (page - 1) * limit,
+		Limit:  limit,
+	}
+}
+
+var libraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "browse the library (artists|albums|tracks)",
+	Long: `browse the library
+this command lists what is stored in the database, grouped by artist or album
+the listings can be filtered, sorted and paged with the flags
+	library artists --sort tracks --desc
+	library albums --artist fonsi
+	library tracks --album vida --page 2
+`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		cmd.Help()
+	},
+}
+
+var libraryArtistsCmd = &cobra.Command{
+	Use:   "artists",
+	Short: "list the artists of the library",
+	Long: `list the artists of the library
+sort keys: name, albums, tracks
+`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		views.LibraryArtistsDisplay(libraryArgs(cmd), client)
+	},
+}
+
+var libraryAlbumsCmd = &cobra.Command{
+	Use:   "albums",
+	Short: "list the albums of the library",
+	Long: `list the albums of the library
+sort keys: name, artist, year, tracks, duration
+`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		views.LibraryAlbumsDisplay(libraryArgs(cmd), client)
+	},
+}
+
+var libraryTracksCmd = &cobra.Command{
+	Use:   "tracks",
+	Short: "list the tracks of the library",
+	Long: `list the tracks of the library
+sort keys: name, artist, album, year, duration
+the hash shown before each track can be used with "play"
+`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		views.LibraryTracksDisplay(libraryArgs(cmd), client)
+	},
+}
+
+var libraryPlayCmd = &cobra.Command{
+	Use:   "play --album <album> | --artist <artist>",
+	Short: "add a whole album or artist to the queue",
+	Long: `add a whole album or artist to the queue
+the album and artist names are case insensitive and must match exactly
+album tracks are queued in track number order
+`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		args := libraryArgs(cmd)
+		if args.Album == "" && args.Artist == "" {
+			fmt.Println("--album or --artist required")
+			return
+		}
+		controller.LibraryPlay(args, client)
+	},
+}
+
+// cachedMusicsCompletion completes the hashes of the cached musics
+func cachedMusicsCompletion(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if client == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var hashes []string
+	for _, music := range controller.GetCachedMusics(client) {
+		hashes = append(hashes, music.Hash[:shared.HashPrefixLength]+"\t"+music.Name)
+	}
+	return hashes, cobra.ShellCompDirectiveNoFileComp
+}
+
+var cacheRemoveCmd = &cobra.Command{
+	Use:               "rm <hash|name>",
+	Short:             "remove a song from the cache",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: cachedMusicsCompletion,
+	Long: `remove a song from the cache
+  the song is found by the hash prefix shown by "cache" or by its name
+  songs in a playlist can't be removed, remove them from the playlist first
+  `,
+	Run: func(_ *cobra.Command, args []string) {
+		controller.RemoveCachedMusic(strings.Join(args, " "), client)
+	},
+}
+
+var cachePinCmd = &cobra.Command{
+	Use:               "pin <hash|name>",
+	Short:             "keep a song in the cache",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: cachedMusicsCompletion,
+	Long: `keep a song in the cache
+  pinned songs are never removed by "cache clean" nor by the cache size and age limits
+  `,
+	Run: func(_ *cobra.Command, args []string) {
+		controller.PinCachedMusic(strings.Join(args, " "), true, client)
+	},
+}
+
+var cacheUnpinCmd = &cobra.Command{
+	Use:               "unpin <hash|name>",
+	Short:             "let the cache cleanup remove a pinned song",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: cachedMusicsCompletion,
+	Long: `let the cache cleanup remove a pinned song
+  this command undoes "cache pin"
+  `,
+	Run: func(_ *cobra.Command, args []string) {
+		controller.PinCachedMusic(strings.Join(args, " "), false, client)
+	},
+}
+
+var cacheExportCmd = &cobra.Command{
+	Use:   "export <hash> <path>",
+	Short: "write a cached song to a file",
+	Args:  cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return cachedMusicsCompletion(cmd, args, toComplete)
+	},
+	Long: `write a cached song to a file
+  the song is written as mp3, if the path is a directory the file is named after the song
+  `,
+	Run: func(_ *cobra.Command, args []string) {
+		path := controller.ExportCachedMusic(args[0], args[1], client)
+		fmt.Println("💾 exported to", path)
+	},
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "check the cache for corrupted songs",
+	Long: `check the cache for corrupted songs
+  this command re-hashes the data of every cached song and reports the ones that changed
+  `,
+	Run: func(_ *cobra.Command, _ []string) {
+		views.CacheVerifyDisplay(client)
+	},
+}
+
+var tasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "show the downloads",
+	Long: `show the downloads with their progress
+  the number of downloads running at the same time is set by max_downloads in the config
+  the failed and canceled downloads stay listed so they can be retried
+  `,
+	Run: func(_ *cobra.Command, _ []string) {
+		views.DownloadsDisplay(client)
+	},
+}
+
+// downloadId parses the id argument of the tasks subcommands
+func downloadId(args []string) int {
+	id, err := strconv.Atoi(strings.TrimSpace(args[0]))
+	if err != nil {
+		fmt.Println("Invalid task id")
+		os.Exit(1)
+	}
+	return id
+}
+
+var tasksCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "cancel a download",
+	Long: `cancel a queued or running download
+  the download process is stopped, use "tasks retry" to start it again
+  `,
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		controller.CancelDownload(downloadId(args), client)
+	},
+}
+
+var tasksRetryCmd = &cobra.Command{
+	Use:   "retry <id>",
+	Short: "retry a failed or canceled download",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		controller.RetryDownload(downloadId(args), client)
+	},
+}
+
+var enginesCmd = &cobra.Command{
+	Use:   "engines",
+	Short: "list the engines with their status",
+	Long: `list the engines with their status and capabilities
+  the engines are asked by priority, the highest first
+  they are configured in the engines section of the config file
+  `,
+	Run: func(_ *cobra.Command, _ []string) {
+		views.EnginesDisplay(client)
+	},
+}
+
+// podcastNames completes the first argument with the podcast names
+func podcastNames(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if client == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, podcast := range controller.Podcasts(client) {
+		names = append(names, podcast.Name)
+	}
+	return names, cobra.ShellCompDirectiveDefault
+}
+
+var podcastCmd = &cobra.Command{
+	Use:   "podcast",
+	Short: "manage the podcasts",
+	Long: `manage the podcasts
+  the feeds are refreshed every podcast_refresh (1h by default) to get the new episodes
+  `,
+	Run: func(_ *cobra.Command, _ []string) {
+		views.PodcastsDisplay(client)
+	},
+}
+
+var podcastAddCmd = &cobra.Command{
+	Use:   "add <feed url>",
+	Short: "follow a podcast",
+	Long: `follow the podcast of a RSS or Atom feed
+  the podcast is named after the title of the feed, use --name to choose another name
+  `,
+	Example: `  retro podcast add https://feeds.example.com/show.xml
+  retro podcast add https://feeds.example.com/show.xml --name show`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		controller.AddPodcast(strings.TrimSpace(args[0]), name, client)
+	},
+}
+
+var podcastListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the podcasts",
+	Run: func(_ *cobra.Command, _ []string) {
+		views.PodcastsDisplay(client)
+	},
+}
+
+var podcastEpisodesCmd = &cobra.Command{
+	Use:               "episodes <name>",
+	Short:             "list the episodes of a podcast",
+	Long:              `list the episodes of a podcast, the newest first, the downloaded ones are marked`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: podcastNames,
+	Run: func(_ *cobra.Command, args []string) {
+		views.EpisodesDisplay(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var podcastPlayCmd = &cobra.Command{
+	Use:   "play <name> [episode index]",
+	Short: "play an episode of a podcast",
+	Long: `play an episode of a podcast
+  the index is the one shown by "podcast episodes", the newest episode (0) is played by default
+  the episode is downloaded the first time, then it is played from the cache
+  `,
+	Example: `  retro podcast play show
+  retro podcast play show 3`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: podcastNames,
+	Run: func(_ *cobra.Command, args []string) {
+		index := 0
+		if len(args) == 2 {
+			var err error
+			index, err = strconv.Atoi(strings.TrimSpace(args[1]))
+			if err != nil {
+				fmt.Println("Invalid episode index")
+				os.Exit(1)
+			}
+		}
+		controller.PodcastPlay(strings.TrimSpace(args[0]), index, client)
+	},
+}
+
+var podcastRemoveCmd = &cobra.Command{
+	Use:               "remove <name>",
+	Short:             "stop following a podcast",
+	Long:              `stop following a podcast, the downloaded episodes stay in the cache`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: podcastNames,
+	Run: func(_ *cobra.Command, args []string) {
+		controller.RemovePodcast(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var podcastRefreshCmd = &cobra.Command{
+	Use:               "refresh [name]",
+	Short:             "refresh the podcast feeds now",
+	Long:              `refresh the feed of the podcast now, all the podcasts are refreshed without a name`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: podcastNames,
+	Run: func(_ *cobra.Command, args []string) {
+		name := ""
+		if len(args) == 1 {
+			name = strings.TrimSpace(args[0])
+		}
+		controller.RefreshPodcast(name, client)
+	},
+}
+
+var bookmarkCmd = &cobra.Command{
+	Use:   "bookmark",
+	Short: "manage the bookmarks of the current song",
+	Long: `manage the named positions of the current song
+  the songs longer than resume_min (20m by default) also resume where they were left
+  `,
+	Run: func(cmd *cobra.Command, _ []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		views.BookmarksDisplay(all, client)
+	},
+}
+
+var bookmarkAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "bookmark the current position",
+	Long:  `bookmark the current position of the current song, a bookmark with the same name is moved`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		controller.AddBookmark(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var bookmarkListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the bookmarks of the current song",
+	Run: func(cmd *cobra.Command, _ []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		views.BookmarksDisplay(all, client)
+	},
+}
+
+// bookmarkNames completes the first argument with the bookmarks of the current song
+func bookmarkNames(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if client == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, bookmark := range controller.Bookmarks(true, client) {
+		if bookmark.Current {
+			names = append(names, bookmark.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveDefault
+}
+
+var bookmarkGotoCmd = &cobra.Command{
+	Use:   "goto <name>",
+	Short: "seek to a bookmark",
+	Long: `seek to a bookmark of the current song
+  when the current song doesn't have it, the song having it is played from the bookmark
+  `,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: bookmarkNames,
+	Run: func(_ *cobra.Command, args []string) {
+		controller.GotoBookmark(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var bookmarkRemoveCmd = &cobra.Command{
+	Use:               "remove <name>",
+	Short:             "remove a bookmark of the current song",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: bookmarkNames,
+	Run: func(_ *cobra.Command, args []string) {
+		controller.RemoveBookmark(strings.TrimSpace(args[0]), client)
+	},
+}
+
+var enginesPlaylistsCmd = &cobra.Command{
+	Use:   "playlists",
+	Short: "list the playlists of the engines servers",
+	Long: `list the playlists of the servers of the engines like subsonic and jellyfin
+  their urls can be played or added to a playlist
+  `,
+	Example: `  retro play https://music.example.com/rest/getPlaylist?id=abc
+  retro list add mix https://music.example.com/rest/getPlaylist?id=abc`,
+	Run: func(_ *cobra.Command, _ []string) {
+		views.RemotePlaylistsDisplay(client)
+	},
+}
+
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "update the retro",
@@ -37,6 +37,7 @@ func init() {
 	}
 
 	rootCmd.AddCommand(playCmd)
+	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(pauseCmd)
 	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(stopCmd)
@@ -55,6 +56,16 @@ func init() {
 	playlistCmd.AddCommand(playlistRemoveCmd)
 	playlistCmd.AddCommand(playlistAddCmd)
 	playlistCmd.AddCommand(playlistPlayCmd)
+	playlistCmd.AddCommand(playlistMoveCmd)
+	playlistCmd.AddCommand(playlistRenameCmd)
+	playlistCmd.AddCommand(playlistCopyCmd)
+	playlistCmd.AddCommand(playlistMergeCmd)
+	playlistCmd.AddCommand(playlistDescribeCmd)
+	playlistCmd.AddCommand(playlistImportCmd)
+	playlistCmd.AddCommand(playlistExportCmd)
+	playlistCmd.AddCommand(playlistSmartCmd)
+	playlistSmartCmd.AddCommand(playlistSmartCreateCmd)
+	playlistSmartCmd.AddCommand(playlistSmartRemoveCmd)
 
 	logCmd.AddCommand(logErrCmd)
 	logCmd.AddCommand(logInfoCmd)
@@ -62,6 +73,54 @@ func init() {
 
 	rootCmd.AddCommand(cacheCmd)
 	cacheCmd.AddCommand(cleanCacheCmd)
+	cacheCmd.AddCommand(cacheRemoveCmd)
+	cacheCmd.AddCommand(cachePinCmd)
+	cacheCmd.AddCommand(cacheUnpinCmd)
+	cacheCmd.AddCommand(cacheExportCmd)
+	cacheCmd.AddCommand(cacheVerifyCmd)
+
+	rootCmd.AddCommand(libraryCmd)
+	libraryCmd.AddCommand(libraryArtistsCmd)
+	libraryCmd.AddCommand(libraryAlbumsCmd)
+	libraryCmd.AddCommand(libraryTracksCmd)
+	libraryCmd.AddCommand(libraryPlayCmd)
+
+	rootCmd.AddCommand(tasksCmd)
+	tasksCmd.AddCommand(tasksCancelCmd)
+	tasksCmd.AddCommand(tasksRetryCmd)
+
+	rootCmd.AddCommand(enginesCmd)
+	enginesCmd.AddCommand(enginesPlaylistsCmd)
+
+	rootCmd.AddCommand(podcastCmd)
+	podcastCmd.AddCommand(podcastAddCmd)
+	podcastCmd.AddCommand(podcastListCmd)
+	podcastCmd.AddCommand(podcastEpisodesCmd)
+	podcastCmd.AddCommand(podcastPlayCmd)
+	podcastCmd.AddCommand(podcastRemoveCmd)
+	podcastCmd.AddCommand(podcastRefreshCmd)
+
+	rootCmd.AddCommand(bookmarkCmd)
+	bookmarkCmd.AddCommand(bookmarkAddCmd)
+	bookmarkCmd.AddCommand(bookmarkListCmd)
+	bookmarkCmd.AddCommand(bookmarkGotoCmd)
+	bookmarkCmd.AddCommand(bookmarkRemoveCmd)
 
 	rootCmd.AddCommand(updateCmd)
+
+	searchCmd.Flags().Bool("local", false, "search only the library")
+	playlistAddCmd.Flags().Int("at", -1, "insert at this index instead of appending")
+	playlistExportCmd.Flags().Bool("files", false, "extract the songs from the cache next to the playlist file")
+	playlistMergeCmd.Flags().String("into", "", "playlist receiving the songs, the first playlist by default")
+	podcastAddCmd.Flags().String("name", "", "name of the podcast, the title of the feed by default")
+	bookmarkCmd.Flags().Bool("all", false, "list the bookmarks of every song")
+	bookmarkListCmd.Flags().Bool("all", false, "list the bookmarks of every song")
+
+	libraryCmd.PersistentFlags().String("artist", "", "filter by artist")
+	libraryCmd.PersistentFlags().String("album", "", "filter by album")
+	libraryCmd.PersistentFlags().String("source", "", "filter by source (local, youtube...)")
+	libraryCmd.PersistentFlags().String("sort", "name", "sort key")
+	libraryCmd.PersistentFlags().Bool("desc", false, "sort in descending order")
+	libraryCmd.PersistentFlags().Int("page", 1, "page number")
+	libraryCmd.PersistentFlags().Int("limit", 50, "results per page, 0 for all")
 }
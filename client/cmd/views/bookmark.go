@@ -0,0 +1,34 @@
+package views
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/Malwarize/retro/client/controller"
+	"github.com/Malwarize/retro/shared"
+)
+
+func BookmarksDisplay(all bool, client *rpc.Client) {
+	bookmarks := controller.Bookmarks(all, client)
+	if len(bookmarks) == 0 {
+		fmt.Println("No bookmarks")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🔖 Bookmarks"))
+	fmt.Println()
+	for index, bookmark := range bookmarks {
+		printTreeBranch(index, len(bookmarks))
+		fmt.Print(" " + shared.DurationToString(bookmark.Position) + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Print(bookmark.Name)
+		if all {
+			// the musics having the bookmarks, the playing one stands out
+			if bookmark.Current {
+				fmt.Print(GetTheme().RunningStyle.Render(" · " + bookmark.Music))
+			} else {
+				fmt.Print(GetTheme().ColoredTextStyle.Render(" · " + bookmark.Music))
+			}
+		}
+		fmt.Println()
+	}
+}
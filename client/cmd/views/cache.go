@@ -2,8 +2,10 @@ package views
 
 import (
 	"fmt"
-	"github.com/Malwarize/retro/shared"
 	"net/rpc"
+	"sort"
+
+	"github.com/Malwarize/retro/shared"
 
 	"github.com/Malwarize/retro/client/controller"
 )
@@ -21,14 +23,50 @@ import (
 //			)
 //		}
 //	}
+func CacheUsageDisplay(client *rpc.Client) {
+	usage := controller.GetCacheUsage(client)
+	limit := "no limit"
+	if usage.MaxSize > 0 {
+		limit = "limit " + shared.FormatSize(usage.MaxSize)
+	}
+	if usage.MaxAge > 0 {
+		limit += ", max age " + usage.MaxAge.String()
+	}
+	fmt.Println(
+		GetTheme().PositionStyle.Render(
+			fmt.Sprintf(
+				"💽 %s used by %d musics, %s evictable (%s)",
+				shared.FormatSize(usage.Total),
+				usage.Count,
+				shared.FormatSize(usage.Evictable),
+				limit,
+			),
+		),
+	)
+	sources := make([]string, 0, len(usage.Sources))
+	for source := range usage.Sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		fmt.Println(
+			GetTheme().PositionStyle.Render(
+				"   " + emojiesType[source] + " " + source + " " + shared.FormatSize(usage.Sources[source]),
+			),
+		)
+	}
+	fmt.Println()
+}
+
 func CacheDisplay(client *rpc.Client) {
 	songs := controller.GetCachedMusics(client)
+	fmt.Print(GetTheme().PositionStyle.Render("📁 Cache\n"))
+	fmt.Print("\n")
+	CacheUsageDisplay(client)
 	if len(songs) == 0 {
 		fmt.Println("No music in cache")
 		return
 	}
-	fmt.Print(GetTheme().PositionStyle.Render("📁 Cache\n"))
-	fmt.Print("\n")
 
 	for l, _ := range songs {
 		if l == len(songs)-1 {
@@ -38,7 +76,28 @@ func CacheDisplay(client *rpc.Client) {
 		}
 		fmt.Print(songs[l].Hash[:shared.HashPrefixLength])
 		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		if songs[l].Pinned {
+			fmt.Print("📌 ")
+		}
 		fmt.Println(songs[l].Name)
 	}
 
 }
+
+func CacheVerifyDisplay(client *rpc.Client) {
+	corrupted := controller.VerifyCache(client)
+	if len(corrupted) == 0 {
+		fmt.Println(GetTheme().PositionStyle.Render("✅ Cache is healthy"))
+		return
+	}
+	fmt.Println(GetTheme().FailStyle.Render(failedEmojie, " Corrupted musics :"))
+	fmt.Println()
+	for l, song := range corrupted {
+		printTreeBranch(l, len(corrupted))
+		fmt.Print(song.Hash[:shared.HashPrefixLength])
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Println(song.Name)
+	}
+	fmt.Println()
+	fmt.Println(GetTheme().PositionStyle.Render("remove them with \"cache rm <hash>\" and play them again"))
+}
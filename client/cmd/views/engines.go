@@ -0,0 +1,56 @@
+package views
+
+import (
+	"fmt"
+	"net/rpc"
+	"strings"
+
+	"github.com/Malwarize/retro/client/controller"
+)
+
+func EnginesDisplay(client *rpc.Client) {
+	engines := controller.Engines(client)
+	if len(engines) == 0 {
+		fmt.Println("No engines")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🔌 Engines"))
+	fmt.Println()
+	for index, engine := range engines {
+		printTreeBranch(index, len(engines))
+		fmt.Print(emojiesType[engine.Name], " ", engine.Name, " ")
+		switch {
+		case !engine.Enabled:
+			fmt.Println(GetTheme().StoppedStyle.Render("disabled"))
+		case !engine.Available:
+			fmt.Println(GetTheme().FailStyle.Render(failedEmojie, engine.Error))
+		default:
+			fmt.Print(GetTheme().RunningStyle.Render("available"))
+			details := fmt.Sprintf(" · priority %d", engine.Priority)
+			if engine.MaxResults > 0 {
+				details += fmt.Sprintf(" · %d results", engine.MaxResults)
+			}
+			details += " · " + strings.Join(engine.Capabilities, ", ")
+			fmt.Print(
+				GetTheme().ColoredTextStyle.Render(details),
+			)
+			fmt.Println()
+		}
+	}
+}
+
+func RemotePlaylistsDisplay(client *rpc.Client) {
+	playlists := controller.RemotePlaylists(client)
+	if len(playlists) == 0 {
+		fmt.Println("No remote playlists")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🌐 Remote playlists"))
+	fmt.Println()
+	for index, playlist := range playlists {
+		printTreeBranch(index, len(playlists))
+		fmt.Print(emojiesType[playlist.Engine], " ", playlist.Name, " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Printf("%d songs · %s\n", playlist.Songs, playlist.Url)
+	}
+}
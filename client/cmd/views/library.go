@@ -0,0 +1,97 @@
+package views
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/Malwarize/retro/client/controller"
+	"github.com/Malwarize/retro/shared"
+)
+
+const unknownTag = "Unknown"
+
+func orUnknown(s string) string {
+	if s == "" {
+		return unknownTag
+	}
+	return s
+}
+
+func printTreeBranch(index, size int) {
+	if index == size-1 {
+		fmt.Print(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render("└──["))
+	} else {
+		fmt.Print(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render("├──["))
+	}
+}
+
+// printNextPage tells how to get the next page when the page is full
+func printNextPage(args shared.LibraryArgs, size int) {
+	if args.Limit > 0 && size == args.Limit {
+		fmt.Println()
+		fmt.Println(
+			GetTheme().PositionStyle.Render(
+				fmt.Sprintf("➡️  more results with --page %d", args.Offset/args.Limit+2),
+			),
+		)
+	}
+}
+
+func LibraryArtistsDisplay(args shared.LibraryArgs, client *rpc.Client) {
+	artists := controller.LibraryArtists(args, client)
+	if len(artists) == 0 {
+		fmt.Println("No artists in library")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🎤 Artists"))
+	fmt.Println()
+	for index, artist := range artists {
+		printTreeBranch(index, len(artists))
+		fmt.Print(" " + orUnknown(artist.Name) + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Printf("%d albums, %d tracks\n", artist.Albums, artist.Tracks)
+	}
+	printNextPage(args, len(artists))
+}
+
+func LibraryAlbumsDisplay(args shared.LibraryArgs, client *rpc.Client) {
+	albums := controller.LibraryAlbums(args, client)
+	if len(albums) == 0 {
+		fmt.Println("No albums in library")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("💿 Albums"))
+	fmt.Println()
+	for index, album := range albums {
+		printTreeBranch(index, len(albums))
+		fmt.Print(" " + orUnknown(album.Name) + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Print(orUnknown(album.Artist))
+		if album.Year != 0 {
+			fmt.Printf(" %d", album.Year)
+		}
+		fmt.Printf(" · %d tracks · %s\n", album.Tracks, shared.DurationToString(album.Duration))
+	}
+	printNextPage(args, len(albums))
+}
+
+func LibraryTracksDisplay(args shared.LibraryArgs, client *rpc.Client) {
+	tracks := controller.LibraryTracks(args, client)
+	if len(tracks) == 0 {
+		fmt.Println("No tracks in library")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🎵 Tracks"))
+	fmt.Println()
+	for index, track := range tracks {
+		printTreeBranch(index, len(tracks))
+		fmt.Print(track.Hash[:shared.HashPrefixLength])
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Print(emojiesType[track.Source], " ", track.Meta.String())
+		if track.Meta.Duration != 0 {
+			fmt.Print(" ", reformatDuration(track.Meta.Duration))
+		}
+		fmt.Println()
+	}
+	printNextPage(args, len(tracks))
+}
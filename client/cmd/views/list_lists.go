@@ -8,7 +8,7 @@ import (
 )
 
 func PlayListsDisplay(client *rpc.Client) {
-	playlists := controller.GetPlayListsNames(client)
+	playlists := controller.GetPlayLists(client)
 	if len(playlists) == 0 {
 		fmt.Println("No playlists")
 		return
@@ -17,12 +17,18 @@ func PlayListsDisplay(client *rpc.Client) {
 	fmt.Println()
 
 	for index, playlist := range playlists {
-		if index == len(playlists)-1 {
-			fmt.Print(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render("└──[ "))
-		} else {
-			fmt.Print(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render("├──[ "))
+		printTreeBranch(index, len(playlists))
+		fmt.Print(" " + playlist.Name + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Printf(
+			"%d songs · updated %s",
+			playlist.Songs,
+			playlist.UpdatedAt.Format("2006-01-02 15:04"),
+		)
+		if playlist.Description != "" {
+			fmt.Print(" · ", playlist.Description)
 		}
-		fmt.Println(playlist)
+		fmt.Println()
 	}
 }
 
@@ -35,11 +41,43 @@ func PlayListMusicsDisplay(name string, client *rpc.Client) {
 	fmt.Println(GetTheme().PositionStyle.Render("🎧 Playlist: ") + name)
 	fmt.Println()
 	for index, song := range songs {
-		if index == len(songs)-1 {
-			fmt.Print(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render("└──[ "))
-		} else {
-			fmt.Print(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render("├──[ "))
-		}
+		printTreeBranch(index, len(songs))
+		fmt.Print(index)
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
 		fmt.Println(song)
 	}
 }
+
+func PlayListImportDisplay(path, name string, client *rpc.Client) {
+	reply := controller.ImportPlayList(path, name, client)
+	fmt.Println(GetTheme().PositionStyle.Render("📥 Imported: ") + fmt.Sprintf("%d songs to %s", reply.Added, name))
+	if len(reply.Unresolved) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println(GetTheme().PositionStyle.Render(fmt.Sprintf("⚠️  %d unresolved", len(reply.Unresolved))))
+	fmt.Println()
+	for index, entry := range reply.Unresolved {
+		printTreeBranch(index, len(reply.Unresolved))
+		fmt.Print(" " + entry.String() + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Println(entry.Reason)
+	}
+}
+
+func SmartPlayListsDisplay(client *rpc.Client) {
+	playlists := controller.GetSmartPlayLists(client)
+	if len(playlists) == 0 {
+		fmt.Println("No smart playlists")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🧠 Smart playlists"))
+	fmt.Println()
+
+	for index, playlist := range playlists {
+		printTreeBranch(index, len(playlists))
+		fmt.Print(" " + playlist.Name + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Printf("%d songs · %s\n", playlist.Songs, playlist.Rule)
+	}
+}
@@ -3,7 +3,6 @@ package views
 import (
 	"net/rpc"
 
-	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/Malwarize/retro/client/controller"
@@ -11,10 +10,23 @@ import (
 )
 
 func addToPlayListCallback(m model) error {
-	i := m.selectList.Index()
+	picked := m.picked()
+	if len(picked) > 1 {
+		return controller.AddSelected(
+			shared.SelectArgs{
+				Queries:      pickedQueries(picked),
+				PlayListName: m.args[0].(string),
+				Position:     m.args[1].(int),
+			},
+			m.client,
+		)
+	}
 	_, err := controller.DetectAndAddToPlayList(
-		m.args[0].(string),
-		m.selectList.Items()[i].(searchResultItem).desc,
+		shared.AddToPlayListArgs{
+			PlayListName: m.args[0].(string),
+			Query:        picked[0].desc,
+			Position:     m.args[1].(int),
+		},
 		m.client,
 	)
 	return err
@@ -22,33 +34,29 @@ func addToPlayListCallback(m model) error {
 
 func AddToPlayListQuitMessage(m model) string {
 	return GetTheme().QuitTextStyle.Render(
-		"🔋 Adding music " + m.selectList.Items()[m.selectList.Index()].(searchResultItem).title + " to playlist " + m.args[0].(string),
+		"🔋 Adding " + m.pickedName("music") + " to playlist " + m.args[0].(string),
 	)
 }
 
 func (m model) AddSearch() tea.Msg {
-	var results []list.Item
-	musics, err := controller.DetectAndAddToPlayList(m.args[0].(string), m.query, m.client)
-	if err != nil {
-		return searchDone{nil, err}
-	}
-	for _, music := range musics {
-		results = append(results, searchResultItem{
-			title:    music.Title,
-			desc:     music.Destination,
-			ftype:    music.Type,
-			duration: shared.DurationToString(music.Duration),
-		})
-	}
-	return searchDone{
-		results: results,
-	}
+	return m.startSearch(
+		controller.DetectAndAddToPlayList(
+			shared.AddToPlayListArgs{
+				PlayListName: m.args[0].(string),
+				Query:        m.query,
+				Position:     m.args[1].(int),
+				Source:       librarySource,
+			},
+			m.client,
+		),
+	)
 }
 
-func SearchThenAddToPlayList(playlist, query string, client *rpc.Client) error {
+// SearchThenAddToPlayList adds the music at position in the playlist, a negative position appends
+func SearchThenAddToPlayList(playlist, query string, position int, client *rpc.Client) error {
 	model := NewModel(client, query)
 	model.callback = addToPlayListCallback
-	model.args = []any{playlist}
+	model.args = []any{playlist, position}
 	model.quitMessage = AddToPlayListQuitMessage
 	model.initCmd = model.AddSearch
 	p := tea.NewProgram(model)
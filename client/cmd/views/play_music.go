@@ -4,43 +4,48 @@ import (
 	"math/rand"
 	"net/rpc"
 
-	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/Malwarize/retro/client/controller"
 	"github.com/Malwarize/retro/shared"
 )
 
+// playCallback plays the picked song, several picked songs are queued in order
+// while they download in parallel
 func playCallback(m model) error {
-	i := m.selectList.Index()
-	_, err := controller.DetectAndPlay(m.selectList.Items()[i].(searchResultItem).desc, m.client)
+	picked := m.picked()
+	if len(picked) > 1 {
+		return controller.AddSelected(
+			shared.SelectArgs{Queries: pickedQueries(picked)},
+			m.client,
+		)
+	}
+	_, err := controller.DetectAndPlay(
+		shared.DetectArgs{Query: picked[0].desc},
+		m.client,
+	)
 	return err
 }
 
 func PlayQuitMessage(m model) string {
 	randEmoji := playingEmojies[rand.Intn(len(playingEmojies))]
 	return GetTheme().QuitTextStyle.Render(
-		randEmoji + " Playing song " + m.selectList.Items()[m.selectList.Index()].(searchResultItem).title + ", this may take a while if download needed",
+		randEmoji + " Playing " + m.pickedName("song") + ", this may take a while if download needed",
 	)
 }
 
+// PlaySearch plays the detected query, the other queries are searched
+// in the library first then in the engines
 func (m model) PlaySearch() tea.Msg {
-	var results []list.Item
-	musics, err := controller.DetectAndPlay(m.query, m.client)
-	if err != nil {
-		return searchDone{nil, err}
-	}
-	for _, music := range musics {
-		results = append(results, searchResultItem{
-			title:    music.Title,
-			desc:     music.Destination,
-			ftype:    music.Type,
-			duration: shared.DurationToString(music.Duration),
-		})
-	}
-	return searchDone{
-		results: results,
-	}
+	return m.startSearch(
+		controller.DetectAndPlay(
+			shared.DetectArgs{
+				Query:  m.query,
+				Source: librarySource,
+			},
+			m.client,
+		),
+	)
 }
 
 func SearchThenSelect(query string, client *rpc.Client) error {
@@ -49,6 +54,7 @@ func SearchThenSelect(query string, client *rpc.Client) error {
 	model.callback = playCallback
 	model.quitMessage = PlayQuitMessage
 	model.initCmd = model.PlaySearch
+	model.canAddToPlaylist = true
 	if _, err := p.Run(); err != nil {
 		return err
 	}
@@ -0,0 +1,59 @@
+package views
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/Malwarize/retro/client/controller"
+	"github.com/Malwarize/retro/shared"
+)
+
+func PodcastsDisplay(client *rpc.Client) {
+	podcasts := controller.Podcasts(client)
+	if len(podcasts) == 0 {
+		fmt.Println("No podcasts")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🎙️ Podcasts"))
+	fmt.Println()
+	for index, podcast := range podcasts {
+		printTreeBranch(index, len(podcasts))
+		fmt.Print(" " + podcast.Name + " ")
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		details := fmt.Sprintf("%d episodes", podcast.Episodes)
+		if podcast.Author != "" {
+			details += " · " + podcast.Author
+		}
+		if !podcast.RefreshedAt.IsZero() && podcast.RefreshedAt.Unix() > 0 {
+			details += " · refreshed " + podcast.RefreshedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Println(details)
+	}
+}
+
+func EpisodesDisplay(name string, client *rpc.Client) {
+	episodes := controller.PodcastEpisodes(name, client)
+	if len(episodes) == 0 {
+		fmt.Println("No episodes")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("🎙️ " + name))
+	fmt.Println()
+	for index, episode := range episodes {
+		printTreeBranch(index, len(episodes))
+		fmt.Print(episode.Index)
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		if episode.Cached {
+			fmt.Print(emojiesType["cache"], " ")
+		}
+		fmt.Print(episode.Title)
+		var details string
+		if !episode.Published.IsZero() {
+			details += " · " + episode.Published.Format("2006-01-02")
+		}
+		if episode.Duration > 0 {
+			details += " · " + shared.DurationToString(episode.Duration)
+		}
+		fmt.Println(GetTheme().ColoredTextStyle.Render(details))
+	}
+}
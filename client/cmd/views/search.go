@@ -0,0 +1,36 @@
+package views
+
+import (
+	"net/rpc"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Malwarize/retro/client/controller"
+)
+
+func (m model) Search() tea.Msg {
+	if m.args[0].(bool) {
+		return searchDone{sources: []string{librarySource}}
+	}
+	sources, err := controller.SearchSources(m.client)
+	if err != nil {
+		return searchDone{err: err}
+	}
+	return searchDone{sources: sources}
+}
+
+// SearchOnlyThenSelect is like SearchThenSelect without detecting the query type,
+// local restricts the search to the library
+func SearchOnlyThenSelect(query string, local bool, client *rpc.Client) error {
+	model := NewModel(client, query)
+	model.callback = playCallback
+	model.canAddToPlaylist = true
+	model.args = []any{local}
+	model.quitMessage = PlayQuitMessage
+	model.initCmd = model.Search
+	p := tea.NewProgram(model)
+	if _, err := p.Run(); err != nil {
+		return err
+	}
+	return nil
+}
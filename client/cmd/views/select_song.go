@@ -10,29 +10,70 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/Malwarize/retro/client/controller"
 	"github.com/Malwarize/retro/shared"
 )
 
+// librarySource is the search source of the cached musics
+const librarySource = "cache"
+
 type searchResultItem struct {
 	title    string
 	desc     string
 	ftype    string
 	duration string
+	artist   string
+	album    string
+	selected bool
 }
 
 func (i searchResultItem) Title() string {
-	if i.ftype == "cache" {
-		return i.title
+	if i.selected {
+		return "✅ " + i.title
 	}
 	return i.title
 }
 
 func (i searchResultItem) Description() string {
-	return emojiesType[i.ftype] + " " + i.ftype + " " + i.duration
+	desc := emojiesType[i.ftype] + " " + i.ftype + " " + i.duration
+	if i.artist != "" {
+		desc += " " + i.artist
+	}
+	if i.album != "" {
+		desc += " (" + i.album + ")"
+	}
+	return desc
 }
 
 func (i searchResultItem) FilterValue() string { return "" }
 
+// playlistItem is a playlist the selected songs can be added to
+type playlistItem struct {
+	name  string
+	songs int
+}
+
+func (i playlistItem) Title() string { return i.name }
+
+func (i playlistItem) Description() string { return fmt.Sprintf("%d songs", i.songs) }
+
+func (i playlistItem) FilterValue() string { return "" }
+
+func searchItems(musics []shared.SearchResult) []list.Item {
+	var items []list.Item
+	for _, music := range musics {
+		items = append(items, searchResultItem{
+			title:    music.Title,
+			desc:     music.Destination,
+			ftype:    music.Type,
+			duration: shared.DurationToString(music.Duration),
+			artist:   music.Artist,
+			album:    music.Album,
+		})
+	}
+	return items
+}
+
 type model struct {
 	client *rpc.Client
 	query  string
@@ -49,6 +90,21 @@ type model struct {
 	searchState int
 	quit        bool
 	mu          *sync.Mutex
+
+	// the sources are searched one by one, their results are added as they come
+	// and their next page is searched when the selection reaches the end of the list
+	sources []string       // the sources which may have more results
+	offsets map[string]int // results received from each source
+	pending int            // pages being searched
+
+	// with canAddToPlaylist "a" lists the playlists, the selected songs
+	// are added to the one chosen instead of being played
+	canAddToPlaylist bool
+	choosing         bool
+	playlists        list.Model
+	playlist         string
+
+	err error // error of the picked songs, shown instead of the quit message
 }
 
 func (m model) Init() tea.Cmd {
@@ -63,13 +119,88 @@ func NewModel(client *rpc.Client, query string) *model {
 	spin.Spinner = spinner.Points
 	spin.Style = GetTheme().SpinnerStyle
 	return &model{
-		client: client,
-		query:  query,
-		spin:   spin,
-		mu:     &sync.Mutex{},
+		client:  client,
+		query:   query,
+		spin:    spin,
+		mu:      &sync.Mutex{},
+		offsets: map[string]int{},
+	}
+}
+
+// startSearch turns the reply of a detection into the first page of the library,
+// the other sources are searched next
+func (m model) startSearch(reply shared.DetectReply, err error) tea.Msg {
+	if err != nil {
+		return searchDone{err: err}
+	}
+	if !reply.Searched {
+		return searchDone{detected: true}
+	}
+	sources, err := controller.SearchSources(m.client)
+	if err != nil {
+		return searchDone{err: err}
+	}
+	return searchDone{
+		sources: sources,
+		first: &pageDone{
+			source:  librarySource,
+			results: searchItems(reply.Results),
+		},
 	}
 }
 
+// searchPage searches the results of the source after offset
+func (m model) searchPage(source string, offset int) tea.Cmd {
+	return func() tea.Msg {
+		musics, err := controller.Search(
+			shared.SearchArgs{
+				Query:  m.query,
+				Source: source,
+				Offset: offset,
+			},
+			m.client,
+		)
+		return pageDone{
+			source:  source,
+			results: searchItems(musics),
+			err:     err,
+		}
+	}
+}
+
+// nextPages searches the next page of the sources having more results
+func (m model) nextPages() (model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, source := range m.sources {
+		m.pending++
+		cmds = append(cmds, m.searchPage(source, m.offsets[source]))
+	}
+	if m.searchState == shared.Finished && m.pending > 0 {
+		cmds = append(cmds, m.selectList.StartSpinner())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// addPage adds the results to the list, a source without results has no more pages
+func (m model) addPage(page pageDone) model {
+	if page.err != nil || len(page.results) == 0 {
+		for i, source := range m.sources {
+			if source == page.source {
+				m.sources = append(m.sources[:i:i], m.sources[i+1:]...)
+				break
+			}
+		}
+		return m
+	}
+	m.offsets[page.source] += len(page.results)
+	if m.searchState != shared.Finished {
+		m.selectList = NewList(nil)
+		m.searchState = shared.Finished
+	}
+	m.selectList.SetItems(append(m.selectList.Items(), page.results...))
+	return m
+}
+
 func NewList(items []list.Item) list.Model {
 	listModel := list.New(items, GetTheme().ListDelegate, 50, 14)
 	listModel.Title = "Select a song 👇"
@@ -79,10 +210,124 @@ func NewList(items []list.Item) list.Model {
 	return listModel
 }
 
+// picked returns the selected songs in the order of the list, or the current one
+func (m model) picked() []searchResultItem {
+	var picked []searchResultItem
+	for _, item := range m.selectList.Items() {
+		if item := item.(searchResultItem); item.selected {
+			picked = append(picked, item)
+		}
+	}
+	if len(picked) == 0 && len(m.selectList.Items()) > 0 {
+		picked = append(picked, m.selectList.SelectedItem().(searchResultItem))
+	}
+	return picked
+}
+
+// pickedName names the picked songs for the quit messages
+func (m model) pickedName(kind string) string {
+	picked := m.picked()
+	if len(picked) == 1 {
+		return kind + " " + picked[0].title
+	}
+	return fmt.Sprintf("%d %ss", len(picked), kind)
+}
+
+func pickedQueries(picked []searchResultItem) []string {
+	var queries []string
+	for _, item := range picked {
+		queries = append(queries, item.desc)
+	}
+	return queries
+}
+
+// toggle selects or unselects the current song
+func (m model) toggle() (model, tea.Cmd) {
+	i := m.selectList.Index()
+	item, ok := m.selectList.SelectedItem().(searchResultItem)
+	if !ok {
+		return m, nil
+	}
+	item.selected = !item.selected
+	cmd := m.selectList.SetItem(i, item)
+	selected := 0
+	for _, other := range m.selectList.Items() {
+		if other.(searchResultItem).selected {
+			selected++
+		}
+	}
+	m.selectList.Title = "Select a song 👇"
+	if selected > 0 {
+		m.selectList.Title = fmt.Sprintf("Select songs 👇 %d selected", selected)
+	}
+	return m, cmd
+}
+
+// choosePlaylist lists the playlists the picked songs can be added to
+func (m model) choosePlaylist() model {
+	var items []list.Item
+	for _, playlist := range controller.GetPlayLists(m.client) {
+		items = append(items, playlistItem{
+			name:  playlist.Name,
+			songs: playlist.Songs,
+		})
+	}
+	if len(items) == 0 {
+		m.selectList.Title = "No playlists, create one with retro list create"
+		return m
+	}
+	m.playlists = NewList(items)
+	m.playlists.Title = "Add " + m.pickedName("song") + " to 👇"
+	m.choosing = true
+	return m
+}
+
+func addPickedQuitMessage(m model) string {
+	return GetTheme().QuitTextStyle.Render(
+		"🔋 Adding " + m.pickedName("music") + " to playlist " + m.playlist,
+	)
+}
+
+func playlistUpdate(msg tea.Msg, m model) (model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			m.choosing = false
+			return m, nil
+		case "enter":
+			m.playlist = m.playlists.SelectedItem().(playlistItem).name
+			m.err = controller.AddSelected(
+				shared.SelectArgs{
+					Queries:      pickedQueries(m.picked()),
+					PlayListName: m.playlist,
+					Position:     -1,
+				},
+				m.client,
+			)
+			m.quitMessage = addPickedQuitMessage
+			m.quit = true
+			return m, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	m.playlists, cmd = m.playlists.Update(msg)
+	return m, cmd
+}
+
 func (m model) View() string {
 	if m.quit {
+		if m.err != nil {
+			return GetTheme().QuitTextStyle.Render(
+				GetTheme().FailStyle.Render(failedEmojie + " " + m.err.Error()),
+			)
+		}
 		return m.quitMessage(m)
 	}
+	if m.choosing {
+		return GetTheme().DocStyle.Render(m.playlists.View())
+	}
 	if m.searchState == shared.Finished {
 		return GetTheme().DocStyle.Render(m.selectList.View())
 	}
@@ -101,19 +346,35 @@ func selectUpdate(msg tea.Msg, m model) (model, tea.Cmd) {
 		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
 		}
-		if msg.String() == "enter" {
-			m.callback(m)
+		switch msg.String() {
+		case "enter":
+			m.err = m.callback(m)
 			m.quit = true
 			return m, tea.Quit
+		case " ":
+			return m.toggle()
+		case "a":
+			if m.canAddToPlaylist {
+				return m.choosePlaylist(), nil
+			}
 		}
 	}
 
 	var cmd tea.Cmd
 	m.selectList, cmd = m.selectList.Update(msg)
+	if _, ok := msg.(tea.KeyMsg); ok && m.pending == 0 && len(m.sources) > 0 &&
+		m.selectList.Index() == len(m.selectList.Items())-1 {
+		var next tea.Cmd
+		m, next = m.nextPages()
+		cmd = tea.Batch(cmd, next)
+	}
 	return m, cmd
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyMsg); ok && m.choosing {
+		return playlistUpdate(msg, m)
+	}
 	switch t := msg.(type) {
 	case tea.KeyMsg:
 		switch t.String() {
@@ -121,15 +382,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 	case searchDone:
-		if t.err != nil {
+		if t.err != nil || t.detected {
 			return m, tea.Quit
 		}
-		if len(t.results) == 0 {
+		m.sources = t.sources
+		if t.first != nil {
+			m = m.addPage(*t.first)
+		}
+		// the first page of the sources not searched yet
+		var cmds []tea.Cmd
+		for _, source := range m.sources {
+			if _, ok := m.offsets[source]; !ok {
+				m.pending++
+				cmds = append(cmds, m.searchPage(source, 0))
+			}
+		}
+		if m.pending == 0 && m.searchState != shared.Finished {
 			return m, tea.Quit
 		}
-		m.selectList = NewList(t.results)
-		m.searchState = shared.Finished
-		return selectUpdate(msg, m)
+		if m.searchState == shared.Finished && m.pending > 0 {
+			cmds = append(cmds, m.selectList.StartSpinner())
+		}
+		return m, tea.Batch(cmds...)
+	case pageDone:
+		m.pending--
+		listed := m.searchState == shared.Finished
+		m = m.addPage(t)
+		switch {
+		case m.searchState != shared.Finished:
+			if m.pending == 0 {
+				// nothing found
+				return m, tea.Quit
+			}
+		case m.pending == 0:
+			m.selectList.StopSpinner()
+		case !listed:
+			return m, m.selectList.StartSpinner()
+		}
+		return m, nil
 	}
 	if m.searchState == shared.Finished {
 		return selectUpdate(msg, m)
@@ -137,7 +427,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return spinnerUpdate(msg, m)
 }
 
+// searchDone starts the search of the sources, first is the page
+// already searched by the detection, detected tells the query was
+// played without searching
 type searchDone struct {
+	sources  []string
+	first    *pageDone
+	detected bool
+	err      error
+}
+
+// pageDone is a page of the results of a source
+type pageDone struct {
+	source  string
 	results []list.Item
 	err     error
 }
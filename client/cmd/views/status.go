@@ -38,16 +38,25 @@ func DisplayStatus(client *rpc.Client) {
 	} else {
 
 		currentMusicName := queue[status.CurrMusicIndex]
+		if status.CurrMusicMeta.Title != "" {
+			currentMusicName = status.CurrMusicMeta.String()
+		}
 
 		currentPosition := status.CurrMusicPosition
 		currentPositionStr := reformatDuration(currentPosition)
 
 		totalDurationStr := reformatDuration(status.CurrMusicDuration)
 
-		prog := progress.New(progress.WithSolidFill(GetTheme().MainColorStyle), progress.WithWidth(40))
-		prog.SetPercent(0.5)
-		prog.ShowPercentage = false
-		fmt.Println(GetTheme().ProgressStyle.Render(prog.ViewAs(currentPosition.Seconds() / status.CurrMusicDuration.Seconds())))
+		if status.CurrMusicLive {
+			// the live streams have no duration to show the progress of
+			totalDurationStr = "LIVE"
+			fmt.Println(GetTheme().ProgressStyle.Render("🔴 LIVE"))
+		} else {
+			prog := progress.New(progress.WithSolidFill(GetTheme().MainColorStyle), progress.WithWidth(40))
+			prog.SetPercent(0.5)
+			prog.ShowPercentage = false
+			fmt.Println(GetTheme().ProgressStyle.Render(prog.ViewAs(currentPosition.Seconds() / status.CurrMusicDuration.Seconds())))
+		}
 
 		fmt.Println("   "+playingEmojies[rand.Intn(len(playingEmojies))], currentMusicName)
 		fmt.Println(GetTheme().PositionStyle.Copy().Inherit(GetTheme().ColoredTextStyle).Render(currentPositionStr, " / ", totalDurationStr))
@@ -106,8 +115,12 @@ func DisplayStatus(client *rpc.Client) {
 		}
 		switch task.Type {
 		case shared.Downloading:
+			progress := ""
+			if task.Progress > 0 {
+				progress = fmt.Sprintf(" %.0f%%", task.Progress)
+			}
 			fmt.Println(
-				GetTheme().TaskStyle.Render(tasksEmojies[task.Type], "Downloading ", target),
+				GetTheme().TaskStyle.Render(tasksEmojies[task.Type], "Downloading ", target+progress),
 			)
 		case shared.Searching:
 			fmt.Println(GetTheme().TaskStyle.Render(tasksEmojies[task.Type], "Searching ", target))
@@ -10,10 +10,16 @@ import (
 // Emoji and status mappings
 var (
 	emojiesType = map[string]string{
-		"youtube": "🎬",
-		"cache":   "💾",
-		"file":    "🎵",
-		"dir":     "📁",
+		"youtube":    "🎬",
+		"soundcloud": "☁️",
+		"radio":      "📻",
+		"podcast":    "🎙️",
+		"subsonic":   "🗄️",
+		"jellyfin":   "🪼",
+		"cache":      "💾",
+		"file":       "🎵",
+		"local":      "🎵",
+		"dir":        "📁",
 	}
 
 	playingEmojies = []string{
@@ -0,0 +1,40 @@
+package views
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/Malwarize/retro/client/controller"
+	"github.com/Malwarize/retro/shared"
+)
+
+func DownloadsDisplay(client *rpc.Client) {
+	downloads := controller.Downloads(client)
+	if len(downloads) == 0 {
+		fmt.Println("No downloads")
+		return
+	}
+	fmt.Println(GetTheme().PositionStyle.Render("📥 Downloads"))
+	fmt.Println()
+	for index, download := range downloads {
+		printTreeBranch(index, len(downloads))
+		fmt.Print(download.Id)
+		fmt.Print(GetTheme().ColoredTextStyle.Render("] "))
+		fmt.Print(emojiesType[download.Engine], " ", download.Target, " ")
+		switch download.State {
+		case shared.DownloadRunning:
+			fmt.Printf("%.1f%%", download.Percent)
+			if download.Speed != "" {
+				fmt.Print(" · ", download.Speed)
+			}
+			if download.ETA != "" {
+				fmt.Print(" · ETA ", download.ETA)
+			}
+			fmt.Println()
+		case shared.DownloadFailed, shared.DownloadCanceled:
+			fmt.Println(GetTheme().FailStyle.Render(failedEmojie, download.State.String()+":", download.Error))
+		default:
+			fmt.Println(download.State.String())
+		}
+	}
+}
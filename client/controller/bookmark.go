@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"fmt"
+	"net/rpc"
+	"os"
+
+	"github.com/Malwarize/retro/shared"
+)
+
+func AddBookmark(name string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCAddBookmark", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func RemoveBookmark(name string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCRemoveBookmark", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func Bookmarks(all bool, client *rpc.Client) []shared.BookmarkInfo {
+	var reply []shared.BookmarkInfo
+	err := client.Call("Player.RPCBookmarks", all, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func GotoBookmark(name string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCGotoBookmark", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
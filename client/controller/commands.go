@@ -5,6 +5,7 @@ import (
 	"github.com/Malwarize/retro/config"
 	"net/rpc"
 	"os"
+	"path/filepath"
 
 	"github.com/Malwarize/retro/shared"
 )
@@ -108,9 +109,28 @@ func GetPlayerStatus(client *rpc.Client) shared.Status {
 	return reply
 }
 
-func DetectAndPlay(query string, client *rpc.Client) ([]shared.SearchResult, error) {
+func DetectAndPlay(args shared.DetectArgs, client *rpc.Client) (shared.DetectReply, error) {
+	var reply shared.DetectReply
+	err := client.Call("Player.RPCDetectAndPlay", args, &reply)
+	return reply, err
+}
+
+func Search(args shared.SearchArgs, client *rpc.Client) ([]shared.SearchResult, error) {
 	var reply []shared.SearchResult
-	err := client.Call("Player.RPCDetectAndPlay", query, &reply)
+	err := client.Call("Player.RPCSearch", args, &reply)
+	return reply, err
+}
+
+// AddSelected adds the selected search results in order, the online ones download in parallel
+func AddSelected(args shared.SelectArgs, client *rpc.Client) error {
+	var reply int
+	return client.Call("Player.RPCAddSelected", args, &reply)
+}
+
+// SearchSources returns the library and the engines the queries can be searched in
+func SearchSources(client *rpc.Client) ([]string, error) {
+	var reply []string
+	err := client.Call("Player.RPCSearchSources", 0, &reply)
 	return reply, err
 }
 
@@ -144,6 +164,16 @@ func GetCachedMusics(client *rpc.Client) []shared.NameHash {
 	return reply
 }
 
+func GetCacheUsage(client *rpc.Client) shared.CacheUsage {
+	var reply shared.CacheUsage
+	err := client.Call("Player.RPCGetCacheUsage", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
 func CleanCache(client *rpc.Client) {
 	var reply int
 	err := client.Call("Player.RPCCleanCache", 0, &reply)
@@ -153,6 +183,103 @@ func CleanCache(client *rpc.Client) {
 	}
 }
 
+func RemoveCachedMusic(target string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCRemoveCachedMusic", target, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func PinCachedMusic(target string, pin bool, client *rpc.Client) {
+	args := shared.PinArgs{Target: target, Pin: pin}
+	var reply int
+	err := client.Call("Player.RPCPinCachedMusic", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// ExportCachedMusic writes the cached music to path, if path is a directory
+// the music is written in it under its name
+func ExportCachedMusic(target string, path string, client *rpc.Client) string {
+	var reply shared.MusicFile
+	err := client.Call("Player.RPCExportCachedMusic", target, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, reply.Name+".mp3")
+	}
+	err = os.WriteFile(path, reply.Data, 0o644)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return path
+}
+
+func VerifyCache(client *rpc.Client) []shared.NameHash {
+	var reply []shared.NameHash
+	err := client.Call("Player.RPCVerifyCache", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func Downloads(client *rpc.Client) []shared.DownloadTask {
+	var reply []shared.DownloadTask
+	err := client.Call("Player.RPCDownloads", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func CancelDownload(id int, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCCancelDownload", id, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func RetryDownload(id int, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCRetryDownload", id, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func Engines(client *rpc.Client) []shared.EngineInfo {
+	var reply []shared.EngineInfo
+	err := client.Call("Player.RPCEngines", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func RemotePlaylists(client *rpc.Client) []shared.RemotePlaylist {
+	var reply []shared.RemotePlaylist
+	err := client.Call("Player.RPCRemotePlaylists", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
 var client *rpc.Client
 
 func GetClient() (*rpc.Client, error) {
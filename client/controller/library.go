@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"fmt"
+	"net/rpc"
+	"os"
+
+	"github.com/Malwarize/retro/shared"
+)
+
+func LibraryArtists(args shared.LibraryArgs, client *rpc.Client) []shared.LibraryArtist {
+	var reply []shared.LibraryArtist
+	err := client.Call("Player.RPCLibraryArtists", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func LibraryAlbums(args shared.LibraryArgs, client *rpc.Client) []shared.LibraryAlbum {
+	var reply []shared.LibraryAlbum
+	err := client.Call("Player.RPCLibraryAlbums", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func LibraryTracks(args shared.LibraryArgs, client *rpc.Client) []shared.LibraryTrack {
+	var reply []shared.LibraryTrack
+	err := client.Call("Player.RPCLibraryTracks", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func LibraryPlay(args shared.LibraryArgs, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCLibraryPlay", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/rpc"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Malwarize/retro/shared"
 )
@@ -18,6 +20,16 @@ func GetPlayListsNames(client *rpc.Client) []string {
 	return reply
 }
 
+func GetPlayLists(client *rpc.Client) []shared.PlayListInfo {
+	var reply []shared.PlayListInfo
+	err := client.Call("Player.RPCPlayLists", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
 func CreatePlayList(name string, client *rpc.Client) {
 	args := name
 	var reply int
@@ -39,12 +51,10 @@ func RemovePlayList(name string, client *rpc.Client) {
 }
 
 func DetectAndAddToPlayList(
-	name string,
-	query string,
+	args shared.AddToPlayListArgs,
 	client *rpc.Client,
-) ([]shared.SearchResult, error) {
-	args := shared.AddToPlayListArgs{PlayListName: name, Query: query}
-	var reply []shared.SearchResult
+) (shared.DetectReply, error) {
+	var reply shared.DetectReply
 	err := client.Call("Player.RPCDetectAndAddToPlayList", args, &reply)
 	return reply, err
 }
@@ -73,6 +83,20 @@ func RemoveMusicFromPlayList(name string, indexOrName shared.IntOrString, client
 	}
 }
 
+func MoveMusicInPlayList(name string, from, to int, client *rpc.Client) {
+	args := shared.MoveMusicInPlayListArgs{
+		PlayListName: name,
+		From:         from,
+		To:           to,
+	}
+	var reply int
+	err := client.Call("Player.RPCMoveMusicInPlayList", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
 func PlayListPlayMusic(lname string, indexOrName shared.IntOrString, client *rpc.Client) {
 	args := shared.PlayListPlayMusicArgs{
 		PlayListName: lname,
@@ -95,3 +119,148 @@ func PlayListPlayAll(name string, client *rpc.Client) {
 		os.Exit(1)
 	}
 }
+
+func RenamePlayList(name, newName string, client *rpc.Client) {
+	args := shared.RenamePlayListArgs{
+		PlayListName: name,
+		NewName:      newName,
+	}
+	var reply int
+	err := client.Call("Player.RPCRenamePlayList", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func CopyPlayList(src, dst string, client *rpc.Client) {
+	args := shared.CopyPlayListArgs{
+		Source:      src,
+		Destination: dst,
+	}
+	var reply int
+	err := client.Call("Player.RPCCopyPlayList", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func MergePlayLists(names []string, into string, client *rpc.Client) {
+	args := shared.MergePlayListsArgs{
+		PlayListNames: names,
+		Into:          into,
+	}
+	var reply int
+	err := client.Call("Player.RPCMergePlayLists", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func DescribePlayList(name, description string, client *rpc.Client) {
+	args := shared.DescribePlayListArgs{
+		PlayListName: name,
+		Description:  description,
+	}
+	var reply int
+	err := client.Call("Player.RPCDescribePlayList", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// ImportPlayList reads the playlist file and adds its songs to the playlist
+func ImportPlayList(path, name string, client *rpc.Client) shared.ImportPlayListReply {
+	entries, err := shared.ReadPlayListFile(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	args := shared.ImportPlayListArgs{
+		PlayListName: name,
+		Entries:      entries,
+	}
+	var reply shared.ImportPlayListReply
+	err = client.Call("Player.RPCImportPlayList", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+// ExportPlayList writes the playlist to a file, the locations are the source keys of the songs
+// or, when files is set, the songs extracted from the cache next to the playlist file
+func ExportPlayList(name, path string, files bool, client *rpc.Client) int {
+	var entries []shared.PlayListEntry
+	err := client.Call("Player.RPCExportPlayList", name, &entries)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if files {
+		// out.m3u8 gets its songs in out/
+		dir := strings.TrimSuffix(path, filepath.Ext(path))
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for i, entry := range entries {
+			var music shared.MusicFile
+			err := client.Call("Player.RPCExportCachedMusic", entry.Hash, &music)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			file := strings.ReplaceAll(music.Name, string(os.PathSeparator), "_") + ".mp3"
+			err = os.WriteFile(filepath.Join(dir, file), music.Data, 0o644)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			entries[i].Location = filepath.ToSlash(filepath.Join(filepath.Base(dir), file))
+		}
+	}
+	err = shared.WritePlayListFile(path, name, entries)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return len(entries)
+}
+
+func GetSmartPlayLists(client *rpc.Client) []shared.SmartPlayList {
+	var reply []shared.SmartPlayList
+	err := client.Call("Player.RPCSmartPlayLists", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func CreateSmartPlayList(name, rule string, client *rpc.Client) {
+	args := shared.SmartPlayListArgs{
+		Name: name,
+		Rule: rule,
+	}
+	var reply int
+	err := client.Call("Player.RPCCreateSmartPlayList", args, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func RemoveSmartPlayList(name string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCRemoveSmartPlayList", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+	"net/rpc"
+	"os"
+
+	"github.com/Malwarize/retro/shared"
+)
+
+func AddPodcast(url, name string, client *rpc.Client) {
+	var reply int
+	err := client.Call(
+		"Player.RPCAddPodcast",
+		shared.AddPodcastArgs{
+			Url:  url,
+			Name: name,
+		},
+		&reply,
+	)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func Podcasts(client *rpc.Client) []shared.PodcastInfo {
+	var reply []shared.PodcastInfo
+	err := client.Call("Player.RPCPodcasts", 0, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func PodcastEpisodes(name string, client *rpc.Client) []shared.EpisodeInfo {
+	var reply []shared.EpisodeInfo
+	err := client.Call("Player.RPCPodcastEpisodes", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return reply
+}
+
+func PodcastPlay(name string, index int, client *rpc.Client) {
+	var reply int
+	err := client.Call(
+		"Player.RPCPodcastPlay",
+		shared.PodcastPlayArgs{
+			Name:  name,
+			Index: index,
+		},
+		&reply,
+	)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func RemovePodcast(name string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCRemovePodcast", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func RefreshPodcast(name string, client *rpc.Client) {
+	var reply int
+	err := client.Call("Player.RPCRefreshPodcast", name, &reply)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,16 +23,48 @@ var (
 )
 
 type Config struct {
-	RetroPath     string        `json:"retro_path"`     // path to retro
-	PathYTDL      string        `json:"path_ytldpl"`    // path to yt-dlp
-	PathFFmpeg    string        `json:"path_ffmpeg"`    // path to ffmpeg
-	PathFFprobe   string        `json:"path_ffprobe"`   // path to ffprobe
-	SearchTimeout time.Duration `json:"search_timeout"` // search timeout
-	Theme         string        `json:"theme"`          // UI theme
-	DBPath        string        `json:"db_path"`        // path to the database
-	DiscordRPC    bool          `json:"discord_rpc"`    // Discord Rich Presence
-	LogFile       string        `json:"log_file"`       // path to the log file
-	ServerPort    string        `json:"server_port"`    // port to run the server on
+	RetroPath      string        `json:"retro_path"`      // path to retro
+	PathYTDL       string        `json:"path_ytldpl"`     // path to yt-dlp
+	PathFFmpeg     string        `json:"path_ffmpeg"`     // path to ffmpeg
+	PathFFprobe    string        `json:"path_ffprobe"`    // path to ffprobe
+	SearchTimeout  time.Duration `json:"search_timeout"`  // search timeout
+	Theme          string        `json:"theme"`           // UI theme
+	DBPath         string        `json:"db_path"`         // path to the database
+	DiscordRPC     bool          `json:"discord_rpc"`     // Discord Rich Presence
+	LogFile        string        `json:"log_file"`        // path to the log file
+	ServerPort     string        `json:"server_port"`     // port to run the server on
+	CacheMaxSize   int64         `json:"cache_max_size"`  // max size of the cache in bytes, 0 for unlimited
+	CacheMaxAge    time.Duration `json:"cache_max_age"`   // remove cached musics not played since, 0 for never
+	MaxDownloads   int           `json:"max_downloads"`   // number of downloads running at the same time
+	PodcastRefresh time.Duration `json:"podcast_refresh"` // how often the podcast feeds are refreshed
+	ResumeMin      time.Duration `json:"resume_min"`      // the musics at least this long resume where they were left, negative for never
+	Offline        bool          `json:"offline"`         // the engines make no network call, only the library is played
+	OfflineRun     bool          `json:"-"`               // the --offline of the server, it lasts the run and is never saved
+
+	Engines map[string]EngineConfig `json:"engines"` // config of each engine by name
+}
+
+// EngineConfig is the config of an engine, the zero fields take the engine defaults
+type EngineConfig struct {
+	Enabled    *bool    `json:"enabled,omitempty"`     // false to disable the engine
+	MaxResults int      `json:"max_results,omitempty"` // number of results of a search
+	Path       string   `json:"path,omitempty"`        // path to the binary of the engine
+	Args       []string `json:"args,omitempty"`        // extra arguments passed to the binary
+	Priority   int      `json:"priority,omitempty"`    // the engines with a higher priority are asked first
+	Url        string   `json:"url,omitempty"`         // url of the server of the engine
+	Username   string   `json:"username,omitempty"`    // user on the server of the engine
+	Password   string   `json:"password,omitempty"`    // password of the user
+	ApiKey     string   `json:"api_key,omitempty"`     // api key of the server of the engine
+}
+
+// IsEnabled tells if the engine is enabled, the engines are enabled unless disabled
+func (ec EngineConfig) IsEnabled() bool {
+	return ec.Enabled == nil || *ec.Enabled
+}
+
+// Engine returns the config of the engine, empty if it has none
+func (c *Config) Engine(name string) EngineConfig {
+	return c.Engines[name]
 }
 
 // Merges file config with default config
@@ -62,7 +96,31 @@ func mergeConfigs(config, defaultConfig *Config) *Config {
 	if config.ServerPort == "" {
 		config.ServerPort = defaultConfig.ServerPort
 	}
-	// No need to check boolean field (DiscordRPC) since false is a meaningful value
+	if config.MaxDownloads <= 0 {
+		config.MaxDownloads = defaultConfig.MaxDownloads
+	}
+	if config.PodcastRefresh <= 0 {
+		config.PodcastRefresh = defaultConfig.PodcastRefresh
+	}
+	if config.ResumeMin == 0 {
+		config.ResumeMin = defaultConfig.ResumeMin
+	}
+	if config.Engines == nil {
+		config.Engines = make(map[string]EngineConfig)
+	}
+	for name, defaultEngine := range defaultConfig.Engines {
+		engine, ok := config.Engines[name]
+		if !ok {
+			config.Engines[name] = defaultEngine
+			continue
+		}
+		if engine.MaxResults <= 0 {
+			engine.MaxResults = defaultEngine.MaxResults
+		}
+		config.Engines[name] = engine
+	}
+	// No need to check boolean fields (DiscordRPC, Offline) since false is a meaningful value
+	// same for the cache limits where 0 means unlimited
 	return config
 }
 
@@ -73,16 +131,27 @@ func initConfig() *Config {
 
 	// Load default config
 	defaultConfig := &Config{
-		RetroPath:     retro_path,
-		PathYTDL:      "yt-dlp",
-		PathFFmpeg:    "ffmpeg",
-		PathFFprobe:   "ffprobe",
-		SearchTimeout: 60 * time.Second,
-		Theme:         "pink",
-		DiscordRPC:    true,
-		LogFile:       filepath.Join(retro_path, "retro.log"),
-		DBPath:        filepath.Join(retro_path, "retro.db"),
-		ServerPort:    "3131",
+		RetroPath:      retro_path,
+		PathYTDL:       "yt-dlp",
+		PathFFmpeg:     "ffmpeg",
+		PathFFprobe:    "ffprobe",
+		SearchTimeout:  60 * time.Second,
+		Theme:          "pink",
+		DiscordRPC:     true,
+		LogFile:        filepath.Join(retro_path, "retro.log"),
+		DBPath:         filepath.Join(retro_path, "retro.db"),
+		ServerPort:     "3131",
+		MaxDownloads:   3,
+		PodcastRefresh: time.Hour,
+		ResumeMin:      20 * time.Minute,
+		Engines: map[string]EngineConfig{
+			"youtube": {
+				MaxResults: 10,
+			},
+			"soundcloud": {
+				MaxResults: 10,
+			},
+		},
 	}
 
 	// Attempt to load from file
@@ -138,14 +207,129 @@ func EditConfigField(field, value string) error {
 		config.LogFile = value
 	case "server_port":
 		config.ServerPort = value
+	case "cache_max_size":
+		if size, err := ParseSize(value); err == nil {
+			config.CacheMaxSize = size
+		} else {
+			return err
+		}
+	case "cache_max_age":
+		if duration, err := time.ParseDuration(value); err == nil {
+			config.CacheMaxAge = duration
+		} else {
+			return err
+		}
+	case "max_downloads":
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			config.MaxDownloads = n
+		} else {
+			return fmt.Errorf("invalid number of downloads: %s", value)
+		}
+	case "podcast_refresh":
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			config.PodcastRefresh = duration
+		} else {
+			return fmt.Errorf("invalid refresh interval: %s", value)
+		}
+	case "resume_min":
+		if duration, err := time.ParseDuration(value); err == nil {
+			config.ResumeMin = duration
+		} else {
+			return err
+		}
+	case "offline":
+		offline, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean: %s", value)
+		}
+		config.Offline = offline
 	default:
-		return errors.New("unknown field: " + field)
+		// the engines fields are engines.<name>.<key>
+		engineField, ok := strings.CutPrefix(field, "engines.")
+		if !ok {
+			return errors.New("unknown field: " + field)
+		}
+		name, key, _ := strings.Cut(engineField, ".")
+		if err := editEngineField(config, name, key, value); err != nil {
+			return err
+		}
 	}
 
 	// Save updated config to file
 	return saveConfig(config)
 }
 
+func editEngineField(config *Config, name, key, value string) error {
+	if config.Engines == nil {
+		config.Engines = make(map[string]EngineConfig)
+	}
+	engine := config.Engines[name]
+	switch key {
+	case "enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean: %s", value)
+		}
+		engine.Enabled = &enabled
+	case "max_results":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of results: %s", value)
+		}
+		engine.MaxResults = n
+	case "path":
+		engine.Path = value
+	case "args":
+		engine.Args = strings.Fields(value)
+	case "priority":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid priority: %s", value)
+		}
+		engine.Priority = n
+	case "url":
+		engine.Url = value
+	case "username":
+		engine.Username = value
+	case "password":
+		engine.Password = value
+	case "api_key":
+		engine.ApiKey = value
+	default:
+		return errors.New("unknown engine field: " + key)
+	}
+	config.Engines[name] = engine
+	return nil
+}
+
+// ParseSize parses a size like 512MB, 2GB or a number of bytes
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.size
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	return int64(size * float64(multiplier)), nil
+}
+
 func saveConfig(config *Config) error {
 	jsonData, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOfflineRunIsNotSaved(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	GetConfig().OfflineRun = true
+	defer func() { GetConfig().OfflineRun = false }()
+	if err := EditConfigField("theme", "blue"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved["offline"] != false {
+		t.Errorf("saved offline = %v, want false", saved["offline"])
+	}
+	if saved["theme"] != "blue" {
+		t.Errorf("saved theme = %v, want blue", saved["theme"])
+	}
+}
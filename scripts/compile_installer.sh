@@ -12,7 +12,7 @@ installer_path=./bin/installer.sh
 function build_binary {
 	local binary_name=$1
 	local binary_source=$2
-	go build -o $binary_name $binary_source
+	go build -tags sqlite_fts5 -o $binary_name $binary_source
 
 	if [ $? -eq 0 ]; then
 		echo "Built $binary_name successfully"
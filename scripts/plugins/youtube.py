@@ -0,0 +1,129 @@
+#!/usr/bin/env python3
+"""youtube engine of retro written as a plugin, it runs yt-dlp
+
+add it to the engines of the config:
+
+    "yt": {
+      "path": "/path/to/youtube.py",
+      "args": ["--cookies", "/home/me/cookies.txt"]
+    }
+
+the args are passed to yt-dlp, retro sends one JSON request on stdin
+and reads the JSON lines printed on stdout, the last one is the response
+"""
+import json
+import os
+import re
+import subprocess
+import sys
+
+YTDLP = os.environ.get("YTDLP", "yt-dlp")
+ARGS = sys.argv[1:]
+PROGRESS = re.compile(r"\[download\]\s+([\d.]+)%.*?at\s+(\S+)\s+ETA\s+(\S+)")
+
+
+def reply(obj):
+    print(json.dumps(obj), flush=True)
+
+
+def ytdlp(*args, **kwargs):
+    return subprocess.run(
+        [YTDLP, *ARGS, *args],
+        capture_output=True,
+        text=True,
+        **kwargs,
+    )
+
+
+def search(req):
+    offset = req.get("offset") or 0
+    res = ytdlp(
+        "--flat-playlist",
+        "--dump-json",
+        "--playlist-start", str(offset + 1),
+        "ytsearch%d:%s" % (offset + (req.get("max_results") or 10), req["query"]),
+    )
+    if res.returncode != 0:
+        return {"error": res.stderr.strip() or "yt-dlp failed"}
+    results = []
+    for line in res.stdout.splitlines():
+        info = json.loads(line)
+        results.append({
+            "title": info.get("title", ""),
+            "url": info.get("url") or "https://www.youtube.com/watch?v=" + info["id"],
+            "duration": info.get("duration") or 0,
+            "artist": info.get("channel") or info.get("uploader") or "",
+        })
+    return {"results": results}
+
+
+def exists(req):
+    url = req["url"]
+    if "youtube.com" not in url and "youtu.be" not in url:
+        return {"exists": False}
+    res = ytdlp("--simulate", "--no-playlist", url)
+    return {"exists": res.returncode == 0}
+
+
+def download(req):
+    output = os.path.join(req["dir"], "%(id)s.%(ext)s")
+    proc = subprocess.Popen(
+        [
+            YTDLP, *ARGS,
+            "--newline",
+            "--no-playlist",
+            "--extract-audio",
+            "--audio-format", "mp3",
+            "--no-warning",
+            "--progress",
+            "--print", "after_move:%(.{id,filepath,title,track,artist,channel,album,duration})j",
+            "--output", output,
+            req["url"],
+        ],
+        stdout=subprocess.PIPE,
+        text=True,
+    )
+    info = None
+    for line in proc.stdout:
+        match = PROGRESS.search(line)
+        if match:
+            reply({
+                "progress": float(match.group(1)),
+                "speed": match.group(2),
+                "eta": match.group(3),
+            })
+        elif line.startswith("{"):
+            info = json.loads(line)
+    if proc.wait() != 0 or info is None:
+        return {"error": "yt-dlp failed"}
+    return {
+        "path": info.get("filepath") or os.path.join(req["dir"], info["id"] + ".mp3"),
+        "title": info.get("track") or info.get("title", ""),
+        "artist": info.get("artist") or info.get("channel") or "",
+        "album": info.get("album") or "",
+        "duration": info.get("duration") or 0,
+    }
+
+
+ACTIONS = {
+    "capabilities": lambda req: {"capabilities": ["search", "download", "metadata"]},
+    "search": search,
+    "exists": exists,
+    "download": download,
+}
+
+
+def main():
+    req = json.loads(sys.stdin.readline())
+    action = ACTIONS.get(req.get("action"))
+    if action is None:
+        reply({"error": "unknown action %s" % req.get("action")})
+        return
+    try:
+        reply(action(req))
+    except Exception as e:
+        reply({"error": str(e)})
+
+
+if __name__ == "__main__":
+    main()
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"flag"
+
 	"github.com/Malwarize/retro/config"
 	"github.com/Malwarize/retro/server/player"
 )
 
 func main() {
+	offline := flag.Bool("offline", false, "make no network call, only play the library")
+	flag.Parse()
+
 	// load config
 	cfg := config.GetConfig()
+	// the config is saved by the settings changes, the flag stays out of it
+	cfg.OfflineRun = *offline
 
 	player.StartIPCServer(cfg.ServerPort)
 }
@@ -1,9 +1,13 @@
 package player
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/Malwarize/retro/config"
 	"github.com/Malwarize/retro/logger"
 	"github.com/Malwarize/retro/server/player/db"
 	"github.com/Malwarize/retro/shared"
@@ -63,13 +67,23 @@ func (p *Player) AddMusicFromFile(
 			)
 			return err
 		}
+		dbMusic := db.Music{
+			Name:   music.Name,
+			Data:   music.Data,
+			Source: "local",
+			Key:    path,
+		}
+		meta, err := p.Director.Converter.ProbeMeta(data)
+		if err != nil {
+			logger.LogWarn(
+				"Failed to read tags of",
+				path,
+				err,
+			)
+		}
+		dbMusic.SetMeta(meta)
 		if err = p.Director.Db.AddMusic(
-			&db.Music{
-				Name:   music.Name,
-				Data:   music.Data,
-				Source: "local",
-				Key:    path,
-			},
+			&dbMusic,
 		); err != nil {
 			return err
 		}
@@ -125,30 +139,276 @@ func (p *Player) AddMusicsFromDir(dirPath string, how callback) error {
 	return nil
 }
 
-// the unique is the unique id of the music in the engine it can be url or id
+// the unique is the unique id of the music in the engine it can be url or id,
+// the download goes through the download manager and blocks until it is done
 func (p *Player) AddMusicFromOnline(
 	unique string,
 	engineName string,
 	how callback,
 ) error {
-	p.addTask(unique, shared.Downloading)
-	music, err := p.Director.Download(engineName, unique)
-	if err != nil || len(music.Data) == 0 {
-		p.errorTask(unique, err)
+	return p.AddMusicFromOnlineWithMeta(
+		unique,
+		engineName,
+		shared.MusicMeta{},
+		how,
+	)
+}
+
+// AddMusicFromOnlineWithMeta is AddMusicFromOnline when the title, artist...
+// are known before the download, like the podcast episodes from their feed
+func (p *Player) AddMusicFromOnlineWithMeta(
+	unique string,
+	engineName string,
+	meta shared.MusicMeta,
+	how callback,
+) error {
+	return p.runDownload(
+		p.downloads.add(unique, engineName, meta, how),
+	)
+}
+
+// AddMusicFromOnlineStreaming is AddMusicFromOnline playing the music while it downloads
+// when the engine can, onStream gets the music as soon as it can play and how
+// is only called when the music couldn't play before it was cached
+func (p *Player) AddMusicFromOnlineStreaming(
+	unique string,
+	engineName string,
+	onStream func(m *Music),
+	how callback,
+) error {
+	d := p.downloads.add(unique, engineName, shared.MusicMeta{}, how)
+	p.downloads.update(d, func(d *download) {
+		d.onStream = onStream
+	})
+	return p.runDownload(d)
+}
+
+// AddMusicsFromPlaylistURL expands the playlist url and downloads its entries in parallel
+// in the background, they are passed to how in the playlist order, each entry has its own
+// task so the failures show in the status without stopping the others
+func (p *Player) AddMusicsFromPlaylistURL(
+	url string,
+	how callback,
+) error {
+	p.addTask(url, shared.Searching)
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+	entries, err := p.Director.ExpandPlaylist(ctx, url)
+	cancel()
+	if err != nil {
+		p.errorTask(url, err)
 		return logger.LogError(
 			logger.GError(
-				"Failed to download music",
+				"Failed to expand playlist",
 				err,
 			),
 		)
 	}
+	p.removeTask(url)
+	if len(entries) == 0 {
+		return logger.LogError(
+			logger.GError(
+				"Playlist is empty",
+			),
+		)
+	}
+	destinations := make([]string, len(entries))
+	for i, entry := range entries {
+		destinations[i] = entry.Destination
+	}
+	go func() {
+		failed := p.addInOrder(
+			destinations,
+			func(i int, collect callback) error {
+				return p.AddMusicFromOnline(
+					entries[i].Destination,
+					entries[i].Type,
+					collect,
+				)
+			},
+			how,
+		)
+		logger.LogInfo(
+			"Playlist",
+			url,
+			"done,",
+			len(entries)-failed,
+			"added,",
+			failed,
+			"failed",
+		)
+	}()
+	return nil
+}
 
-	if how != nil {
-		err := how(*music)
+// AddSelected adds the musics selected in a search to the playlist of args,
+// or to the queue playing them, in their order
+func (p *Player) AddSelected(args shared.SelectArgs) error {
+	if len(args.Queries) == 0 {
+		return logger.LogError(
+			logger.GError(
+				"Nothing selected",
+			),
+		)
+	}
+	how := func(m db.Music) error {
+		pmusic, err := NewMusicFromDb(m)
 		if err != nil {
 			return err
 		}
+		p.Queue.Enqueue(*pmusic)
+		if p.getPlayerState() == shared.Stopped {
+			return p.Play()
+		}
+		return nil
+	}
+	if args.PlayListName != "" {
+		pl, err := p.Director.Db.GetPlaylist(
+			args.PlayListName,
+		)
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to get playlist",
+					err,
+				),
+			)
+		}
+		how = p.playlistAdder(pl.Name, args.Position)
+	}
+	return p.AddMusicsInOrder(args.Queries, how)
+}
+
+// AddMusicsInOrder detects the queries then finds or downloads their musics in parallel
+// in the background, each music is passed to how once the ones before it are, the failed
+// ones are skipped, it fails when none of the queries is a music
+func (p *Player) AddMusicsInOrder(queries []string, how callback) error {
+	kinds := make([]DResults, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			kinds[i] = p.CheckWhatIsThis(query)
+		}(i, query)
 	}
-	p.removeTask(unique)
+	wg.Wait()
+
+	var musics []string
+	var musicKinds []DResults
+	var err error
+	for i, query := range queries {
+		switch kinds[i] {
+		case DUnknown, DDir, DQueue, DPlaylist, DRemotePlaylist, DLive:
+			err = fmt.Errorf("%s is a %s, not a music", query, kinds[i])
+			logger.LogWarn(
+				"Failed to add",
+				query,
+				err,
+			)
+		default:
+			musics = append(musics, query)
+			musicKinds = append(musicKinds, kinds[i])
+		}
+	}
+	if len(musics) == 0 {
+		return logger.LogError(
+			logger.GError(
+				"None of the selected musics can be added",
+				err,
+			),
+		)
+	}
+	go p.addInOrder(
+		musics,
+		func(i int, collect callback) error {
+			return p.findMusicOf(musics[i], musicKinds[i], collect)
+		},
+		how,
+	)
 	return nil
 }
+
+// addInOrder runs find for each query in parallel, the downloads share the slots of
+// the download manager, and passes the musics to how in the order of the queries,
+// it returns the number of musics that couldn't be added once they are all done
+func (p *Player) addInOrder(
+	queries []string,
+	find func(i int, collect callback) error,
+	how callback,
+) int {
+	found := make([]chan *db.Music, len(queries))
+	for i, query := range queries {
+		i := i
+		found[i] = make(chan *db.Music, 1)
+		go p.findMusic(
+			query,
+			func(collect callback) error {
+				return find(i, collect)
+			},
+			found[i],
+			how,
+		)
+	}
+	failed := 0
+	for i, music := range found {
+		m := <-music
+		if m == nil {
+			failed++
+			continue
+		}
+		if err := how(*m); err != nil {
+			failed++
+			logger.LogWarn(
+				"Failed to add",
+				queries[i],
+				err,
+			)
+		}
+	}
+	return failed
+}
+
+// findMusicOf finds or downloads the music of the query detected as whatIsThis
+func (p *Player) findMusicOf(query string, whatIsThis DResults, collect callback) error {
+	switch whatIsThis {
+	case DCache:
+		return p.AddMusicFromHash(query, collect)
+	case DFile:
+		return p.AddMusicFromFile(query, collect)
+	default:
+		return p.AddMusicFromOnline(query, string(whatIsThis), collect)
+	}
+}
+
+// findMusic sends the music found by find to found, or nil when it can't be added,
+// a retry of its failed download passes it to how right away
+func (p *Player) findMusic(
+	query string,
+	find func(collect callback) error,
+	found chan *db.Music,
+	how callback,
+) {
+	var once sync.Once
+	collect := func(m db.Music) error {
+		first := false
+		once.Do(func() {
+			found <- &m
+			first = true
+		})
+		if !first {
+			return how(m)
+		}
+		return nil
+	}
+
+	if err := find(collect); err != nil {
+		logger.LogWarn(
+			"Failed to add",
+			query,
+			err,
+		)
+	}
+	once.Do(func() {
+		found <- nil
+	})
+}
@@ -0,0 +1,69 @@
+package player
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Malwarize/retro/server/player/db"
+	"github.com/Malwarize/retro/shared"
+)
+
+func TestAddInOrder(t *testing.T) {
+	p := newTestPlayer(t)
+	queries := []string{"first", "second", "broken", "third"}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	find := func(i int, collect callback) error {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		// the first queries are the slowest
+		time.Sleep(time.Duration(len(queries)-i) * 20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if queries[i] == "broken" {
+			return errors.New("download failed")
+		}
+		return collect(db.Music{Name: queries[i]})
+	}
+	var added []string
+	how := func(m db.Music) error {
+		added = append(added, m.Name)
+		return nil
+	}
+
+	failed := p.addInOrder(queries, find, how)
+	if failed != 1 {
+		t.Errorf("%d failed, want 1", failed)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added %v, want %v", added, want)
+	}
+	if maxRunning < 2 {
+		t.Errorf("the musics were found one at a time")
+	}
+}
+
+func TestAddSelectedErrors(t *testing.T) {
+	p := newTestPlayer(t)
+	if err := p.Director.Db.AddPlaylist("favorites"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]shared.SelectArgs{
+		"nothing selected": {PlayListName: "favorites"},
+		"missing playlist": {Queries: []string{"anything"}, PlayListName: "removed"},
+		"no music":         {Queries: []string{"favorites", "not a song"}, PlayListName: "favorites"},
+	}
+	for name, args := range tests {
+		if err := p.AddSelected(args); err == nil {
+			t.Errorf("%s: AddSelected succeeded", name)
+		}
+	}
+}
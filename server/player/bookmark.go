@@ -0,0 +1,300 @@
+package player
+
+import (
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	"github.com/Malwarize/retro/shared"
+)
+
+const (
+	// resumeEndMargin is how close to the end a music is considered finished,
+	// its resume position is forgotten
+	resumeEndMargin = 30 * time.Second
+	// resumeSaveInterval is how often the position of the playing music is saved
+	resumeSaveInterval = 30 * time.Second
+)
+
+// resumable tells if the music is long enough to resume where it was left
+func resumable(duration time.Duration) bool {
+	min := config.GetConfig().ResumeMin
+	return min > 0 && duration >= min
+}
+
+// saveResume remembers the position of the current music if it is long enough,
+// a rewound music keeps its position and a finished one forgets it
+func (p *Player) saveResume() {
+	music := p.Queue.GetCurrMusic()
+	if music == nil || music.IsLive() || music.IsDownloading() {
+		return
+	}
+	duration := p.GetCurrMusicDuration()
+	position := p.GetCurrMusicPosition()
+	if !resumable(duration) || position == 0 {
+		return
+	}
+	if duration-position <= resumeEndMargin {
+		position = 0
+	}
+	if err := p.Director.Db.SetResumePosition(music.Name, position); err != nil {
+		logger.LogWarn(
+			"Failed to save the position of",
+			music.Name,
+			err,
+		)
+	}
+}
+
+// restoreResume moves the music to where it was left, the musics
+// already moved by a seek or a bookmark are not changed
+func (p *Player) restoreResume(music *Music) {
+	if music.IsLive() || music.PositionN() != 0 {
+		return
+	}
+	duration := music.DurationD()
+	if !resumable(duration) {
+		return
+	}
+	position, err := p.Director.Db.GetResumePosition(music.Name)
+	if err != nil || position <= 0 || position >= duration {
+		return
+	}
+	logger.LogInfo(
+		"Resuming",
+		music.Name,
+		"at",
+		position,
+	)
+	if err := music.SetPositionD(position); err != nil {
+		logger.LogWarn(
+			"Failed to resume",
+			music.Name,
+			err,
+		)
+	}
+}
+
+// runResumeSaver saves the position of the playing music periodically
+// so it survives a crash of the server, it never returns
+func (p *Player) runResumeSaver() {
+	for {
+		time.Sleep(resumeSaveInterval)
+		if p.getPlayerState() == shared.Playing {
+			p.saveResume()
+		}
+	}
+}
+
+// ####################
+// # Bookmark methods #
+// ####################
+func (p *Player) currentCachedMusic() (*Music, error) {
+	if p.getPlayerState() == shared.Stopped {
+		return nil, logger.LogError(
+			logger.GError(
+				"player is not running",
+			),
+		)
+	}
+	music := p.Queue.GetCurrMusic()
+	if music == nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get current music",
+			),
+		)
+	}
+	if music.IsLive() {
+		return nil, logger.LogError(
+			logger.GError(
+				"Can't bookmark a live stream",
+			),
+		)
+	}
+	return music, nil
+}
+
+// AddBookmark names the current position of the current music,
+// an existing bookmark with the same name is moved
+func (p *Player) AddBookmark(name string) error {
+	music, err := p.currentCachedMusic()
+	if err != nil {
+		return err
+	}
+	if _, err := p.Director.Db.GetMusicByName(music.Name); err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Music is not in the cache",
+			),
+		)
+	}
+	err = p.Director.Db.AddBookmark(db.Bookmark{
+		Music:    music.Name,
+		Name:     name,
+		Position: p.GetCurrMusicPosition(),
+	})
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to add bookmark",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+// RemoveBookmark removes the bookmark of the current music
+func (p *Player) RemoveBookmark(name string) error {
+	music, err := p.currentCachedMusic()
+	if err != nil {
+		return err
+	}
+	err = p.Director.Db.RemoveBookmark(music.Name, name)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to remove bookmark",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+// Bookmarks returns the bookmarks of the current music, or of every music when all is set
+func (p *Player) Bookmarks(all bool) ([]shared.BookmarkInfo, error) {
+	var current string
+	if music := p.Queue.GetCurrMusic(); music != nil && p.getPlayerState() != shared.Stopped {
+		current = music.Name
+	}
+	if !all && current == "" {
+		return nil, logger.LogError(
+			logger.GError(
+				"player is not running",
+			),
+		)
+	}
+	musicName := current
+	if all {
+		musicName = ""
+	}
+	bookmarks, err := p.Director.Db.GetBookmarks(musicName)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get bookmarks",
+				err,
+			),
+		)
+	}
+	var res []shared.BookmarkInfo
+	for _, bookmark := range bookmarks {
+		res = append(res, shared.BookmarkInfo{
+			Music:    bookmark.Music,
+			Name:     bookmark.Name,
+			Position: bookmark.Position,
+			Current:  bookmark.Music == current,
+		})
+	}
+	return res, nil
+}
+
+// GotoBookmark seeks to the bookmark of the current music, when the current
+// music doesn't have it the music having it is played from the bookmark
+func (p *Player) GotoBookmark(name string) error {
+	if music := p.Queue.GetCurrMusic(); music != nil && p.getPlayerState() != shared.Stopped {
+		bookmarks, err := p.Director.Db.GetBookmarks(music.Name)
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to get bookmarks",
+					err,
+				),
+			)
+		}
+		for _, bookmark := range bookmarks {
+			if bookmark.Name == name {
+				return p.Seek(bookmark.Position - p.GetCurrMusicPosition())
+			}
+		}
+	}
+
+	bookmarks, err := p.Director.Db.GetBookmarks("")
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to get bookmarks",
+				err,
+			),
+		)
+	}
+	var found []db.Bookmark
+	for _, bookmark := range bookmarks {
+		if bookmark.Name == name {
+			found = append(found, bookmark)
+		}
+	}
+	if len(found) == 0 {
+		return logger.LogError(
+			logger.GError(
+				"Bookmark not found",
+			),
+		)
+	}
+	if len(found) > 1 {
+		return logger.LogError(
+			logger.GError(
+				"Several musics have the bookmark " + name + ", play the one you want first",
+			),
+		)
+	}
+	bookmark := found[0]
+
+	music := p.Queue.GetMusicByName(bookmark.Music)
+	if music == nil {
+		m, err := p.Director.Db.GetMusicByName(bookmark.Music)
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Music is not in the cache",
+				),
+			)
+		}
+		pmusic, err := NewMusicFromDb(m)
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to decode music",
+					err,
+				),
+			)
+		}
+		p.Queue.Enqueue(*pmusic)
+		music = p.Queue.GetMusicByName(bookmark.Music)
+		if music == nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to enqueue music",
+				),
+			)
+		}
+	}
+	p.saveResume()
+	var seekErr error
+	p.concernSpeakerLock(func() {
+		seekErr = music.SetPositionD(bookmark.Position)
+	})
+	if seekErr != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to seek",
+				seekErr,
+			),
+		)
+	}
+	p.Queue.SetCurrrMusic(music)
+	return p.Play()
+}
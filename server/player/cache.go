@@ -0,0 +1,116 @@
+package player
+
+import (
+	"strings"
+
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	"github.com/Malwarize/retro/shared"
+)
+
+// getCachedMusic finds a cached music by hash prefix or by name
+func (p *Player) getCachedMusic(target string) (db.Music, error) {
+	m, err := p.Director.Db.GetMusicByHashPrefix(target)
+	if err == nil {
+		return m, nil
+	}
+	m, err = p.Director.Db.GetMusicByName(target)
+	if err != nil {
+		return db.Music{}, logger.LogError(
+			logger.GError(
+				"Music not found in cache",
+				err,
+			),
+		)
+	}
+	return m, nil
+}
+
+// RemoveCachedMusic deletes a music from the cache, musics in a playlist are kept
+func (p *Player) RemoveCachedMusic(target string) error {
+	m, err := p.getCachedMusic(target)
+	if err != nil {
+		return err
+	}
+	playlists, err := p.Director.Db.GetMusicPlaylists(m.Name)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to get playlists of music",
+				err,
+			),
+		)
+	}
+	if len(playlists) > 0 {
+		return logger.LogError(
+			logger.GError(
+				m.Name + " is in playlist(s) " + strings.Join(playlists, ", ") + ", remove it from them first",
+			),
+		)
+	}
+	err = p.Director.Db.RemoveMusic(m.Name)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to remove music from cache",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+// PinCachedMusic exempts a music from the cache cleanup and eviction, or undo it
+func (p *Player) PinCachedMusic(target string, pin bool) error {
+	m, err := p.getCachedMusic(target)
+	if err != nil {
+		return err
+	}
+	err = p.Director.Db.SetMusicPinned(m.Name, pin)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to pin music",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+func (p *Player) ExportCachedMusic(target string) (shared.MusicFile, error) {
+	m, err := p.getCachedMusic(target)
+	if err != nil {
+		return shared.MusicFile{}, err
+	}
+	return shared.MusicFile{
+		Name: m.Name,
+		Data: m.Data,
+	}, nil
+}
+
+// VerifyCache returns the cached musics whose data is corrupted
+func (p *Player) VerifyCache() ([]shared.NameHash, error) {
+	musics, err := p.Director.Db.VerifyMusics()
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to verify cache",
+				err,
+			),
+		)
+	}
+	var corrupted []shared.NameHash
+	for _, music := range musics {
+		logger.LogWarn(
+			"Corrupted music in cache",
+			music.Name,
+		)
+		corrupted = append(corrupted, shared.NameHash{
+			Name:   music.Name,
+			Hash:   music.Hash,
+			Pinned: music.Pinned,
+		})
+	}
+	return corrupted, nil
+}
@@ -2,12 +2,18 @@ package player
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Malwarize/retro/config"
 	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
 )
 
 type Converter struct {
@@ -67,6 +73,76 @@ func (c *Converter) ConvertToMP3(inputData []byte) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
+// convertedStream is the mp3 output of ffmpeg converting a stream
+type convertedStream struct {
+	io.ReadCloser
+	input    io.Closer
+	cmd      *exec.Cmd
+	waitOnce sync.Once
+	waitErr  error
+}
+
+func (cs *convertedStream) wait() error {
+	cs.waitOnce.Do(func() {
+		cs.waitErr = cs.cmd.Wait()
+	})
+	return cs.waitErr
+}
+
+// Read fails at the end of the output when ffmpeg or the reading of its input failed,
+// a broken input would otherwise end the output as if it was complete
+func (cs *convertedStream) Read(p []byte) (int, error) {
+	n, err := cs.ReadCloser.Read(p)
+	if err == io.EOF {
+		if waitErr := cs.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close stops ffmpeg and closes its input
+func (cs *convertedStream) Close() error {
+	cs.input.Close()
+	cs.cmd.Process.Kill()
+	cs.wait()
+	return nil
+}
+
+// ConvertStreamToMP3 converts the audio as it arrives, ffmpeg runs until the returned reader is closed
+func (c *Converter) ConvertStreamToMP3(input io.ReadCloser) (io.ReadCloser, error) {
+	cmd := exec.Command(
+		c.ffmpegPath,
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-vn",
+		"-ar", "44100",
+		"-ac", "2",
+		"-b:a", "192k",
+		"-f", "mp3",
+		"pipe:1",
+	)
+	cmd.Stdin = input
+	cmd.Stderr = logger.ERRORLogger.Writer()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Error converting stream to MP3",
+				err,
+			),
+		)
+	}
+	return &convertedStream{
+		ReadCloser: stdout,
+		input:      input,
+		cmd:        cmd,
+	}, nil
+}
+
 func (c *Converter) IsMp3(fileData []byte) (bool, error) {
 	cmd := exec.Command("ffprobe",
 		"-v", "error",
@@ -97,3 +173,83 @@ func (c *Converter) IsMp3(fileData []byte) (bool, error) {
 	isMP3 := fileFormat == "mp3"
 	return isMP3, nil
 }
+
+// ffprobeOutput is the json printed by ffprobe -of json
+type ffprobeOutput struct {
+	Streams []struct {
+		Tags map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		Duration string            `json:"duration"`
+		Tags     map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// leadingInt parses the number at the start of s, for example "3/12" or "2019-05-01"
+func leadingInt(s string) int {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// ProbeMeta reads the ID3/Vorbis tags and the duration of the audio data
+func (c *Converter) ProbeMeta(fileData []byte) (shared.MusicMeta, error) {
+	cmd := exec.Command(
+		c.ffprobePath,
+		"-v", "error",
+		"-show_entries", "format=duration:format_tags:stream_tags",
+		"-of", "json",
+		"-i", "pipe:0", // Read from stdin
+	)
+	cmd.Stdin = bytes.NewReader(fileData)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return shared.MusicMeta{}, logger.LogError(
+			logger.GError(
+				"Error running ffprobe to read metadata",
+				err,
+			),
+			stderr.String(),
+		)
+	}
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return shared.MusicMeta{}, err
+	}
+
+	// vorbis comments (ogg) are stream tags, id3 are format tags
+	// tag keys are case insensitive
+	tags := make(map[string]string)
+	for _, stream := range probe.Streams {
+		for k, v := range stream.Tags {
+			tags[strings.ToLower(k)] = v
+		}
+	}
+	for k, v := range probe.Format.Tags {
+		tags[strings.ToLower(k)] = v
+	}
+
+	meta := shared.MusicMeta{
+		Title:       tags["title"],
+		Artist:      tags["artist"],
+		Album:       tags["album"],
+		TrackNumber: leadingInt(tags["track"]),
+		Year:        leadingInt(tags["date"]),
+	}
+	if meta.Artist == "" {
+		meta.Artist = tags["album_artist"]
+	}
+	if meta.TrackNumber == 0 {
+		meta.TrackNumber = leadingInt(tags["tracknumber"])
+	}
+	if seconds, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		meta.Duration = time.Duration(seconds * float64(time.Second))
+	}
+	return meta, nil
+}
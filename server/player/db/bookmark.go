@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// resume positions and bookmarks are stored in seconds
+
+type Bookmark struct {
+	Music    string // name of the music
+	Name     string
+	Position time.Duration
+}
+
+func (d *Db) InitBookmark() error {
+	_, err := d.db.Exec(
+		`CREATE TABLE IF NOT EXISTS bookmark (
+      music_name TEXT NOT NULL,
+      name TEXT NOT NULL,
+      position INTEGER NOT NULL DEFAULT 0,
+      created_at INTEGER NOT NULL DEFAULT 0,
+      PRIMARY KEY (music_name, name),
+      FOREIGN KEY (music_name) REFERENCES music (name)
+    )`,
+	)
+	return err
+}
+
+// SetResumePosition remembers where the music was left, 0 forgets it
+func (d *Db) SetResumePosition(name string, position time.Duration) error {
+	_, err := d.db.Exec(
+		`UPDATE music SET resume_position = ? WHERE name = ?`,
+		int64(position.Seconds()),
+		name,
+	)
+	return err
+}
+
+func (d *Db) GetResumePosition(name string) (time.Duration, error) {
+	var position int64
+	err := d.db.QueryRow(
+		`SELECT resume_position FROM music WHERE name = ?`,
+		name,
+	).Scan(&position)
+	return time.Duration(position) * time.Second, err
+}
+
+// AddBookmark stores the bookmark, a bookmark with the same name
+// in the same music is moved to the new position
+func (d *Db) AddBookmark(bookmark Bookmark) error {
+	_, err := d.db.Exec(
+		`INSERT INTO bookmark (music_name, name, position, created_at) VALUES (?, ?, ?, ?)
+    ON CONFLICT (music_name, name) DO UPDATE SET position = excluded.position`,
+		bookmark.Music,
+		bookmark.Name,
+		int64(bookmark.Position.Seconds()),
+		time.Now().Unix(),
+	)
+	return err
+}
+
+func (d *Db) RemoveBookmark(musicName, name string) error {
+	result, err := d.db.Exec(
+		`DELETE FROM bookmark WHERE music_name = ? AND name = ?`,
+		musicName,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf(
+			"Bookmark %s not found",
+			name,
+		)
+	}
+	return nil
+}
+
+// GetBookmarks returns the bookmarks of the music in the order of their position,
+// the bookmarks of every music when the name is empty
+func (d *Db) GetBookmarks(musicName string) ([]Bookmark, error) {
+	query := `SELECT bookmark.music_name, bookmark.name, bookmark.position FROM bookmark
+    INNER JOIN music ON music.name = bookmark.music_name`
+	var values []any
+	if musicName != "" {
+		query += ` WHERE bookmark.music_name = ?`
+		values = append(values, musicName)
+	}
+	rows, err := d.db.Query(
+		query+` ORDER BY bookmark.music_name COLLATE NOCASE, bookmark.position`,
+		values...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bookmarks []Bookmark
+	for rows.Next() {
+		var bookmark Bookmark
+		var position int64
+		err := rows.Scan(
+			&bookmark.Music,
+			&bookmark.Name,
+			&position,
+		)
+		if err != nil {
+			return nil, err
+		}
+		bookmark.Position = time.Duration(position) * time.Second
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, rows.Err()
+}
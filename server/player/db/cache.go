@@ -0,0 +1,208 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// notInPlaylist is the condition matching the musics that are not in any playlist
+const notInPlaylist = `music.name NOT IN (SELECT music_name FROM music_playlist)`
+
+// evictableMusic is the condition matching the musics the cache cleanup may remove
+const evictableMusic = notInPlaylist + ` AND music.pinned = 0`
+
+// TouchMusic marks the music as played now, it is used by the cache eviction
+// and the smart playlists
+func (d *Db) TouchMusic(name string) error {
+	_, err := d.db.Exec(
+		`UPDATE music SET last_played_at = ?, play_count = play_count + 1 WHERE name = ?`,
+		time.Now().Unix(),
+		name,
+	)
+	return err
+}
+
+type CacheUsage struct {
+	Total     int64            // size of all the musics
+	Evictable int64            // size of the musics that are not in a playlist nor pinned
+	Sources   map[string]int64 // size per source
+	Count     int
+}
+
+func (d *Db) GetCacheUsage() (CacheUsage, error) {
+	usage := CacheUsage{
+		Sources: make(map[string]int64),
+	}
+	rows, err := d.db.Query(
+		`SELECT source, COUNT(*), SUM(size), SUM(CASE WHEN ` + evictableMusic + ` THEN size ELSE 0 END)
+     FROM music GROUP BY source`,
+	)
+	if err != nil {
+		return usage, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			source    string
+			count     int
+			size      int64
+			evictable int64
+		)
+		if err := rows.Scan(&source, &count, &size, &evictable); err != nil {
+			return usage, err
+		}
+		usage.Sources[source] = size
+		usage.Total += size
+		usage.Evictable += evictable
+		usage.Count += count
+	}
+	return usage, rows.Err()
+}
+
+// EvictMusics removes the least recently played musics that are not in a playlist nor pinned
+// until the total size is under maxSize, and the musics not played since maxAge,
+// a zero maxSize or maxAge disables the limit, the musics named in keep are never removed
+func (d *Db) EvictMusics(maxSize int64, maxAge time.Duration, keep []string) ([]string, error) {
+	var total int64
+	err := d.db.QueryRow(
+		`SELECT COALESCE(SUM(size), 0) FROM music`,
+	).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(
+		`SELECT name, size, last_played_at FROM music WHERE ` + evictableMusic + `
+     ORDER BY last_played_at ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]bool)
+	for _, name := range keep {
+		kept[name] = true
+	}
+	var evict []string
+	for rows.Next() {
+		var (
+			name       string
+			size       int64
+			lastPlayed int64
+		)
+		if err := rows.Scan(&name, &size, &lastPlayed); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if kept[name] {
+			continue
+		}
+		tooBig := maxSize > 0 && total > maxSize
+		tooOld := maxAge > 0 && time.Since(time.Unix(lastPlayed, 0)) > maxAge
+		if !tooBig && !tooOld {
+			// rows are ordered by last play, the next ones are newer
+			break
+		}
+		evict = append(evict, name)
+		total -= size
+	}
+	rows.Close()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, name := range evict {
+		if err := deleteMusic(tx, name); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return evict, nil
+}
+
+// deleteMusic deletes the music with its bookmarks, they would be
+// given to the next music cached with the same name
+func deleteMusic(tx *sql.Tx, name string) error {
+	_, err := tx.Exec(
+		`DELETE FROM music WHERE name = ?`,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`DELETE FROM bookmark WHERE music_name = ?`,
+		name,
+	)
+	return err
+}
+
+// RemoveMusic deletes the music from the cache with its bookmarks
+func (d *Db) RemoveMusic(name string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteMusic(tx, name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *Db) SetMusicPinned(name string, pinned bool) error {
+	_, err := d.db.Exec(
+		`UPDATE music SET pinned = ? WHERE name = ?`,
+		pinned,
+		name,
+	)
+	return err
+}
+
+// GetMusicPlaylists returns the names of the playlists containing the music
+func (d *Db) GetMusicPlaylists(name string) ([]string, error) {
+	rows, err := d.db.Query(
+		`SELECT playlist_name FROM music_playlist WHERE music_name = ?`,
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var playlists []string
+	for rows.Next() {
+		var playlist string
+		if err := rows.Scan(&playlist); err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists, rows.Err()
+}
+
+// VerifyMusics re-hashes the data of every music and returns the ones
+// whose data doesn't match the stored hash
+func (d *Db) VerifyMusics() ([]Music, error) {
+	rows, err := d.db.Query(
+		`SELECT ` + musicColumns + ` FROM music`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var corrupted []Music
+	for rows.Next() {
+		music, err := scanMusic(rows)
+		if err != nil {
+			return nil, err
+		}
+		if hash(music.Data) != music.Hash || int64(len(music.Data)) != music.Size {
+			music.Data = nil
+			corrupted = append(corrupted, music)
+		}
+	}
+	return corrupted, rows.Err()
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func addTestMusic(t *testing.T, d *Db, name string) {
+	t.Helper()
+	err := d.AddMusic(&Music{
+		Name:   name,
+		Source: "file",
+		Key:    "/music/" + name + ".mp3",
+		Data:   []byte("data of " + name),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemovedMusicsLoseTheirBookmarks(t *testing.T) {
+	tests := map[string]func(d *Db) error{
+		"evict": func(d *Db) error {
+			_, err := d.EvictMusics(1, 0, nil)
+			return err
+		},
+		"clean": func(d *Db) error {
+			return d.CleanCache()
+		},
+		"remove": func(d *Db) error {
+			return d.RemoveMusic("get_lucky")
+		},
+	}
+	for name, remove := range tests {
+		t.Run(name, func(t *testing.T) {
+			d, err := LoadDb(filepath.Join(t.TempDir(), "retro.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			addTestMusic(t, d, "get_lucky")
+			err = d.AddBookmark(Bookmark{Music: "get_lucky", Name: "chorus", Position: time.Minute})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := remove(d); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := d.GetMusicByName("get_lucky"); err == nil {
+				t.Fatal("the music is still cached")
+			}
+
+			// a new download with the same name doesn't get the bookmarks
+			addTestMusic(t, d, "get_lucky")
+			bookmarks, err := d.GetBookmarks("get_lucky")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(bookmarks) != 0 {
+				t.Errorf("the new music has the bookmarks %+v", bookmarks)
+			}
+		})
+	}
+}
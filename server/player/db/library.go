@@ -0,0 +1,172 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Malwarize/retro/shared"
+)
+
+type Artist struct {
+	Name   string
+	Albums int
+	Tracks int
+}
+
+type Album struct {
+	Name     string
+	Artist   string
+	Year     int
+	Tracks   int
+	Duration time.Duration
+}
+
+// sort keys accepted by each listing, "name" is the default
+var (
+	artistsOrder = map[string]string{
+		"name":   `artist COLLATE NOCASE`,
+		"albums": `COUNT(DISTINCT album)`,
+		"tracks": `COUNT(*)`,
+	}
+	albumsOrder = map[string]string{
+		"name":     `album COLLATE NOCASE`,
+		"artist":   `MAX(artist) COLLATE NOCASE`,
+		"year":     `MAX(year)`,
+		"tracks":   `COUNT(*)`,
+		"duration": `SUM(duration)`,
+	}
+	tracksOrder = map[string]string{
+		"name":     `title COLLATE NOCASE`,
+		"artist":   `artist COLLATE NOCASE`,
+		"album":    `album COLLATE NOCASE, track_number`,
+		"year":     `year`,
+		"duration": `duration`,
+	}
+)
+
+// orderBy returns the ORDER BY clause of the listing, unknown keys sort by name
+func orderBy(orders map[string]string, args shared.LibraryArgs) string {
+	order, ok := orders[args.Sort]
+	if !ok {
+		order = orders["name"]
+	}
+	if args.Desc {
+		// apply the direction to every column of the order
+		order = strings.ReplaceAll(order, ",", " DESC,") + " DESC"
+	}
+	return ` ORDER BY ` + order
+}
+
+// libraryWhere builds the WHERE clause of the listing filters
+func libraryWhere(args shared.LibraryArgs) (string, []any) {
+	var conditions []string
+	var values []any
+	if args.Artist != "" {
+		conditions = append(conditions, `artist LIKE ?`)
+		values = append(values, "%"+args.Artist+"%")
+	}
+	if args.Album != "" {
+		conditions = append(conditions, `album LIKE ?`)
+		values = append(values, "%"+args.Album+"%")
+	}
+	if args.Source != "" {
+		conditions = append(conditions, `source = ?`)
+		values = append(values, args.Source)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND "), values
+}
+
+// limitOffset returns the paging clause, a zero limit means no limit
+func limitOffset(args shared.LibraryArgs) (string, []any) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	return ` LIMIT ? OFFSET ?`, []any{limit, max(args.Offset, 0)}
+}
+
+func (d *Db) GetArtists(args shared.LibraryArgs) ([]Artist, error) {
+	where, values := libraryWhere(args)
+	paging, pagingValues := limitOffset(args)
+	rows, err := d.db.Query(
+		`SELECT artist, COUNT(DISTINCT album), COUNT(*) FROM music`+
+			where+
+			` GROUP BY artist COLLATE NOCASE`+
+			orderBy(artistsOrder, args)+
+			paging,
+		append(values, pagingValues...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var artists []Artist
+	for rows.Next() {
+		var artist Artist
+		err := rows.Scan(
+			&artist.Name,
+			&artist.Albums,
+			&artist.Tracks,
+		)
+		if err != nil {
+			return nil, err
+		}
+		artists = append(artists, artist)
+	}
+	return artists, rows.Err()
+}
+
+func (d *Db) GetAlbums(args shared.LibraryArgs) ([]Album, error) {
+	where, values := libraryWhere(args)
+	paging, pagingValues := limitOffset(args)
+	rows, err := d.db.Query(
+		`SELECT album, MAX(artist), MAX(year), COUNT(*), SUM(duration) FROM music`+
+			where+
+			` GROUP BY album COLLATE NOCASE`+
+			orderBy(albumsOrder, args)+
+			paging,
+		append(values, pagingValues...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var albums []Album
+	for rows.Next() {
+		var album Album
+		var seconds int64
+		err := rows.Scan(
+			&album.Name,
+			&album.Artist,
+			&album.Year,
+			&album.Tracks,
+			&seconds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		album.Duration = time.Duration(seconds) * time.Second
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+// GetTracks returns the musics of the library without their data
+func (d *Db) GetTracks(args shared.LibraryArgs) ([]Music, error) {
+	where, values := libraryWhere(args)
+	paging, pagingValues := limitOffset(args)
+	rows, err := d.db.Query(
+		`SELECT `+musicInfoColumns+` FROM music`+
+			where+
+			orderBy(tracksOrder, args)+
+			paging,
+		append(values, pagingValues...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMusics(rows)
+}
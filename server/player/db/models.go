@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -11,6 +12,7 @@ import (
 type Db struct {
 	db   *sql.DB
 	path string
+	fts  bool // full text search available
 }
 
 func NewDb(path string) (*Db, error) {
@@ -33,6 +35,52 @@ func (d *Db) Close() error {
 	return d.db.Close()
 }
 
+// addColumn adds the column to the table if it is missing,
+// it is used to migrate databases created by older versions of retro
+func (d *Db) addColumn(table, column, definition string) error {
+	rows, err := d.db.Query(
+		fmt.Sprintf(`PRAGMA table_info(%s)`, table),
+	)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	for rows.Next() {
+		var (
+			cid     int
+			name    string
+			ctype   string
+			notnull int
+			dflt    sql.NullString
+			pk      int
+		)
+		err := rows.Scan(
+			&cid,
+			&name,
+			&ctype,
+			&notnull,
+			&dflt,
+			&pk,
+		)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, name)
+	}
+	rows.Close()
+
+	for _, c := range columns {
+		if c == column {
+			return nil
+		}
+	}
+	_, err = d.db.Exec(
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition),
+	)
+	return err
+}
+
 func LoadDb(
 	path string,
 ) (*Db, error) {
@@ -55,5 +103,23 @@ func LoadDb(
 		return nil, err
 	}
 
+	err = db.InitSmartPlaylist()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.InitPodcast()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.InitBookmark()
+	if err != nil {
+		return nil, err
+	}
+
+	// fts5 is optional, search falls back to LIKE without it
+	db.fts = db.InitMusicFts() == nil
+
 	return db, nil
 }
@@ -5,17 +5,73 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/Malwarize/retro/shared"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Music struct {
-	Name   string
-	Source string
-	Key    string
-	Data   []byte
-	Hash   string
+	Name        string
+	Source      string
+	Key         string
+	Data        []byte
+	Hash        string
+	Title       string
+	Artist      string
+	Album       string
+	Duration    time.Duration
+	TrackNumber int
+	Year        int
+	Size        int64 // size of the data in bytes
+	LastPlayed  time.Time
+	Pinned      bool // pinned musics are never removed by the cache cleanup
+}
+
+// musicColumns is the list of columns scanned by scanMusic
+const musicColumns = `music.name, music.source, music.key, music.data, music.hash,
+  music.title, music.artist, music.album, music.duration, music.track_number, music.year,
+  music.size, music.last_played_at, music.pinned`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMusic(row rowScanner) (Music, error) {
+	var music Music
+	var seconds, lastPlayed int64
+	err := row.Scan(
+		&music.Name,
+		&music.Source,
+		&music.Key,
+		&music.Data,
+		&music.Hash,
+		&music.Title,
+		&music.Artist,
+		&music.Album,
+		&seconds,
+		&music.TrackNumber,
+		&music.Year,
+		&music.Size,
+		&lastPlayed,
+		&music.Pinned,
+	)
+	music.Duration = time.Duration(seconds) * time.Second
+	music.LastPlayed = time.Unix(lastPlayed, 0)
+	return music, err
+}
+
+func scanMusics(rows *sql.Rows) ([]Music, error) {
+	defer rows.Close()
+	var musics []Music
+	for rows.Next() {
+		music, err := scanMusic(rows)
+		if err != nil {
+			return nil, err
+		}
+		musics = append(musics, music)
+	}
+	return musics, rows.Err()
 }
 
 func (d *Db) InitMusic() error {
@@ -26,26 +82,93 @@ func (d *Db) InitMusic() error {
       key TEXT,
       data BLOB,
       hash TEXT UNIQUE NOT NULL,
+      title TEXT NOT NULL DEFAULT '',
+      artist TEXT NOT NULL DEFAULT '',
+      album TEXT NOT NULL DEFAULT '',
+      duration INTEGER NOT NULL DEFAULT 0,
+      track_number INTEGER NOT NULL DEFAULT 0,
+      year INTEGER NOT NULL DEFAULT 0,
+      size INTEGER NOT NULL DEFAULT 0,
+      last_played_at INTEGER NOT NULL DEFAULT 0,
+      pinned INTEGER NOT NULL DEFAULT 0,
+      play_count INTEGER NOT NULL DEFAULT 0,
+      added_at INTEGER NOT NULL DEFAULT 0,
+      resume_position INTEGER NOT NULL DEFAULT 0,
       PRIMARY KEY (source, key)
     )`,
 	)
+	if err != nil {
+		return err
+	}
+	// migrate databases created before the metadata columns
+	columns := [][2]string{
+		{"title", `TEXT NOT NULL DEFAULT ''`},
+		{"artist", `TEXT NOT NULL DEFAULT ''`},
+		{"album", `TEXT NOT NULL DEFAULT ''`},
+		{"duration", `INTEGER NOT NULL DEFAULT 0`},
+		{"track_number", `INTEGER NOT NULL DEFAULT 0`},
+		{"year", `INTEGER NOT NULL DEFAULT 0`},
+		{"size", `INTEGER NOT NULL DEFAULT 0`},
+		{"last_played_at", `INTEGER NOT NULL DEFAULT 0`},
+		{"pinned", `INTEGER NOT NULL DEFAULT 0`},
+		{"play_count", `INTEGER NOT NULL DEFAULT 0`},
+		{"added_at", `INTEGER NOT NULL DEFAULT 0`},
+		{"resume_position", `INTEGER NOT NULL DEFAULT 0`},
+	}
+	for _, c := range columns {
+		if err := d.addColumn("music", c[0], c[1]); err != nil {
+			return err
+		}
+	}
+	// musics cached before the size and last_played_at columns
+	_, err = d.db.Exec(
+		`UPDATE music SET size = LENGTH(data) WHERE size = 0`,
+	)
+	if err != nil {
+		return err
+	}
+	// the musics cached before added_at keep 0, their adding date is unknown
+	_, err = d.db.Exec(
+		`UPDATE music SET last_played_at = ? WHERE last_played_at = 0`,
+		time.Now().Unix(),
+	)
 	return err
 }
 
+// Meta returns the metadata of the music, the title falls back to the name
+func (m Music) Meta() shared.MusicMeta {
+	title := m.Title
+	if title == "" {
+		title = m.Name
+	}
+	return shared.MusicMeta{
+		Title:       title,
+		Artist:      m.Artist,
+		Album:       m.Album,
+		Duration:    m.Duration,
+		TrackNumber: m.TrackNumber,
+		Year:        m.Year,
+	}
+}
+
+// SetMeta fills the metadata fields of the music
+func (m *Music) SetMeta(meta shared.MusicMeta) {
+	m.Title = meta.Title
+	m.Artist = meta.Artist
+	m.Album = meta.Album
+	m.Duration = meta.Duration
+	m.TrackNumber = meta.TrackNumber
+	m.Year = meta.Year
+}
+
 func (d *Db) GetMusic(source string, key string) (Music, error) {
-	var music Music
-	err := d.db.QueryRow(
-		`SELECT name, source, key, data, hash FROM music WHERE source = ? AND key = ?`,
-		source,
-		key,
-	).Scan(
-		&music.Name,
-		&music.Source,
-		&music.Key,
-		&music.Data,
-		&music.Hash,
+	return scanMusic(
+		d.db.QueryRow(
+			`SELECT `+musicColumns+` FROM music WHERE source = ? AND key = ?`,
+			source,
+			key,
+		),
 	)
-	return music, err
 }
 
 func (d *Db) UpdateMusic(
@@ -55,9 +178,10 @@ func (d *Db) UpdateMusic(
 	data []byte,
 ) error {
 	_, err := d.db.Exec(
-		`UPDATE music SET data = ?, hash = ? WHERE name = ? AND source = ? AND key = ?`,
+		`UPDATE music SET data = ?, hash = ?, size = ? WHERE name = ? AND source = ? AND key = ?`,
 		data,
 		hash(data),
+		len(data),
 		name,
 		source,
 		key,
@@ -65,6 +189,43 @@ func (d *Db) UpdateMusic(
 	return err
 }
 
+// UpdateMusicMeta overwrites the metadata of the music with the given name
+func (d *Db) UpdateMusicMeta(name string, meta shared.MusicMeta) error {
+	_, err := d.db.Exec(
+		`UPDATE music SET title = ?, artist = ?, album = ?, duration = ?, track_number = ?, year = ? WHERE name = ?`,
+		meta.Title,
+		meta.Artist,
+		meta.Album,
+		int64(meta.Duration/time.Second),
+		meta.TrackNumber,
+		meta.Year,
+		name,
+	)
+	return err
+}
+
+func (d *Db) insertMusic(name string, music *Music) error {
+	_, err := d.db.Exec(
+		`INSERT INTO music (name, source, key, data, hash, title, artist, album, duration, track_number, year, size, last_played_at, added_at)
+     VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		name,
+		music.Source,
+		music.Key,
+		music.Data,
+		hash(music.Data),
+		music.Title,
+		music.Artist,
+		music.Album,
+		int64(music.Duration/time.Second),
+		music.TrackNumber,
+		music.Year,
+		len(music.Data),
+		time.Now().Unix(),
+		time.Now().Unix(),
+	)
+	return err
+}
+
 // insertUniqueMusicName is a helper function to insert music with a unique name
 func (d *Db) insertUniqueMusicName(music *Music) error {
 	var newName string
@@ -88,15 +249,7 @@ func (d *Db) insertUniqueMusicName(music *Music) error {
 	}
 
 	// Insert music with the new unique name
-	_, err = d.db.Exec(
-		`INSERT INTO music (name, source, key, data, hash) VALUES (?, ?, ?, ?,?)`,
-		newName,
-		music.Source,
-		music.Key,
-		music.Data,
-		hash(music.Data),
-	)
-	return err
+	return d.insertMusic(newName, music)
 }
 
 func (d *Db) AddMusic(music *Music) error {
@@ -117,43 +270,24 @@ func (d *Db) AddMusic(music *Music) error {
 	}
 
 	// If the name is not used, insert the music with hash
-	_, err = d.db.Exec(
-		`INSERT INTO music (name, source, key, data, hash) VALUES (?, ?, ?, ?, ?)`,
-		music.Name,
-		music.Source,
-		music.Key,
-		music.Data,
-		hash(music.Data),
-	)
-	return err
+	return d.insertMusic(music.Name, music)
 }
 
 func (d *Db) GetMusicByName(name string) (Music, error) {
-	var music Music
-	err := d.db.QueryRow(
-		`SELECT name, source, key, data, hash FROM music WHERE name = ?`,
-		name,
-	).Scan(
-		&music.Name,
-		&music.Source,
-		&music.Key,
-		&music.Data,
-		&music.Hash,
+	return scanMusic(
+		d.db.QueryRow(
+			`SELECT `+musicColumns+` FROM music WHERE name = ?`,
+			name,
+		),
 	)
-	return music, err
 }
 
 func (d *Db) GetMusicByHash(hash string) (Music, error) {
-	var music Music
-	err := d.db.QueryRow(
-		`SELECT name, source, key, data, hash FROM music WHERE hash = ?`,
-		hash,
-	).Scan(
-		&music.Name,
-		&music.Source,
-		&music.Key,
-		&music.Data,
-		&music.Hash,
+	music, err := scanMusic(
+		d.db.QueryRow(
+			`SELECT `+musicColumns+` FROM music WHERE hash = ?`,
+			hash,
+		),
 	)
 	if err != nil {
 		return Music{}, err
@@ -162,19 +296,13 @@ func (d *Db) GetMusicByHash(hash string) (Music, error) {
 }
 
 func (d *Db) GetMusicByKeySource(source string, key string) (Music, error) {
-	var music Music
-	err := d.db.QueryRow(
-		`SELECT name, source, key, data, hash FROM music WHERE source = ? AND key = ?`,
-		source,
-		key,
-	).Scan(
-		&music.Name,
-		&music.Source,
-		&music.Key,
-		&music.Data,
-		&music.Hash,
+	return scanMusic(
+		d.db.QueryRow(
+			`SELECT `+musicColumns+` FROM music WHERE source = ? AND key = ?`,
+			source,
+			key,
+		),
 	)
-	return music, err
 }
 
 func hash(data []byte) string {
@@ -188,79 +316,70 @@ func (m Music) GetHash() string {
 }
 
 func (d *Db) GetMusicByHashPrefix(hash_p string) (Music, error) {
-	var music Music
-	err := d.db.QueryRow(
-		`SELECT name, source, key, data, hash FROM music WHERE SUBSTRING(hash, 1, ?) = SUBSTRING(?, 1, ?)`,
-		shared.HashPrefixLength,
-		hash_p,
-		shared.HashPrefixLength,
-	).Scan(
-		&music.Name,
-		&music.Source,
-		&music.Key,
-		&music.Data,
-		&music.Hash,
+	return scanMusic(
+		d.db.QueryRow(
+			`SELECT `+musicColumns+` FROM music WHERE SUBSTRING(hash, 1, ?) = SUBSTRING(?, 1, ?)`,
+			shared.HashPrefixLength,
+			hash_p,
+			shared.HashPrefixLength,
+		),
 	)
-	return music, err
 }
 
-func (d *Db) FilterMusic(query string) ([]Music, error) {
+// GetMusicsByArtist returns the musics of the artist, ordered by album and track number
+func (d *Db) GetMusicsByArtist(artist string) ([]Music, error) {
 	rows, err := d.db.Query(
-		`SELECT name, source, key, data, hash FROM music WHERE name LIKE ?`,
-		fmt.Sprintf("%%%s%%", query),
+		`SELECT `+musicColumns+` FROM music WHERE artist = ? COLLATE NOCASE
+     ORDER BY album, track_number, title`,
+		artist,
 	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var musics []Music
-	for rows.Next() {
-		var music Music
-		err := rows.Scan(
-			&music.Name,
-			&music.Source,
-			&music.Key,
-			&music.Data,
-			&music.Hash,
-		)
-		if err != nil {
-			return nil, err
-		}
-		musics = append(musics, music)
+	return scanMusics(rows)
+}
+
+// GetMusicsByAlbum returns the musics of the album, ordered by track number
+func (d *Db) GetMusicsByAlbum(album string) ([]Music, error) {
+	rows, err := d.db.Query(
+		`SELECT `+musicColumns+` FROM music WHERE album = ? COLLATE NOCASE
+     ORDER BY track_number, title`,
+		album,
+	)
+	if err != nil {
+		return nil, err
 	}
-	return musics, nil
+	return scanMusics(rows)
 }
 
 func (d *Db) CleanCache() error {
-	// Delete music thats not in playlist
-	_, err := d.db.Exec(
-		`DELETE FROM music WHERE hash NOT IN (SELECT hash FROM music INNER JOIN music_playlist ON music.name = music_playlist.music_name)`,
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Delete music thats not in playlist and not pinned, with its bookmarks
+	_, err = tx.Exec(
+		`DELETE FROM bookmark WHERE music_name IN (SELECT name FROM music WHERE ` + evictableMusic + `)`,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`DELETE FROM music WHERE ` + evictableMusic,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (d *Db) GetCachedMusics() ([]Music, error) {
 	rows, err := d.db.Query(
-		`select * from music where hash not in (SELECT hash FROM music INNER JOIN music_playlist ON music.name = music_playlist.music_name)`,
+		`SELECT ` + musicColumns + ` FROM music WHERE hash NOT IN (SELECT hash FROM music INNER JOIN music_playlist ON music.name = music_playlist.music_name)`,
 	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var musics []Music
-	for rows.Next() {
-		var music Music
-		err := rows.Scan(
-			&music.Name,
-			&music.Source,
-			&music.Key,
-			&music.Data,
-			&music.Hash,
-		)
-		if err != nil {
-			return nil, err
-		}
-		musics = append(musics, music)
-	}
-	return musics, nil
+	return scanMusics(rows)
 }
@@ -1,28 +1,65 @@
 package db
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type Playlist struct {
-	Name string
+	Name        string
+	Description string
+	Songs       int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
-func (d *Db) GetPlaylist(name string) (Playlist, error) {
+const playlistColumns = `name, description, created_at, updated_at,
+  (SELECT COUNT(*) FROM music_playlist WHERE playlist_name = playlist.name)`
+
+func scanPlaylist(row rowScanner) (Playlist, error) {
 	var playlist Playlist
-	err := d.db.QueryRow(
-		`SELECT name FROM playlist WHERE name = ?`,
-		name,
-	).Scan(
+	var createdAt, updatedAt int64
+	err := row.Scan(
 		&playlist.Name,
+		&playlist.Description,
+		&createdAt,
+		&updatedAt,
+		&playlist.Songs,
 	)
+	playlist.CreatedAt = time.Unix(createdAt, 0)
+	playlist.UpdatedAt = time.Unix(updatedAt, 0)
 	return playlist, err
 }
 
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// touchPlaylist marks the playlist as updated now
+func touchPlaylist(ex execer, name string) error {
+	_, err := ex.Exec(
+		`UPDATE playlist SET updated_at = ? WHERE name = ?`,
+		time.Now().Unix(),
+		name,
+	)
+	return err
+}
+
+func (d *Db) GetPlaylist(name string) (Playlist, error) {
+	return scanPlaylist(
+		d.db.QueryRow(
+			`SELECT `+playlistColumns+` FROM playlist WHERE name = ?`,
+			name,
+		),
+	)
+}
+
 func (d *Db) GetPlaylists() ([]Playlist, error) {
 	rows, err := d.db.Query(
-		`SELECT name FROM playlist`,
+		`SELECT ` + playlistColumns + ` FROM playlist ORDER BY created_at, rowid`,
 	)
 	if err != nil {
 		return nil, err
@@ -30,10 +67,7 @@ func (d *Db) GetPlaylists() ([]Playlist, error) {
 	defer rows.Close()
 	var playlists []Playlist
 	for rows.Next() {
-		var playlist Playlist
-		err := rows.Scan(
-			&playlist.Name,
-		)
+		playlist, err := scanPlaylist(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -42,10 +76,29 @@ func (d *Db) GetPlaylists() ([]Playlist, error) {
 	return playlists, nil
 }
 
+// AddPlaylist creates the playlist, a smart playlist can't have its name
 func (d *Db) AddPlaylist(plname string) error {
-	_, err := d.db.Exec(
-		`INSERT OR IGNORE INTO playlist (name) VALUES (?)`,
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	smart, err := smartPlaylistExists(tx, plname)
+	if err != nil {
+		return err
+	}
+	if smart {
+		return fmt.Errorf(
+			"Smart playlist %s already exists",
+			plname,
+		)
+	}
+	_, err = tx.Exec(
+		`INSERT OR IGNORE INTO playlist (name, created_at, updated_at) VALUES (?, ?, ?)`,
 		plname,
+		time.Now().Unix(),
+		time.Now().Unix(),
 	)
 	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
 		return fmt.Errorf(
@@ -53,9 +106,199 @@ func (d *Db) AddPlaylist(plname string) error {
 			plname,
 		)
 	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *Db) SetPlaylistDescription(name, description string) error {
+	_, err := d.db.Exec(
+		`UPDATE playlist SET description = ?, updated_at = ? WHERE name = ?`,
+		description,
+		time.Now().Unix(),
+		name,
+	)
 	return err
 }
 
+// playlistExists tells if the playlist exists in the transaction
+func playlistExists(tx *sql.Tx, name string) (bool, error) {
+	var count int
+	err := tx.QueryRow(
+		`SELECT COUNT(*) FROM playlist WHERE name = ?`,
+		name,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// checkNewPlaylistName fails when a playlist or a smart playlist has the name
+func checkNewPlaylistName(tx *sql.Tx, name string) error {
+	exists, err := playlistExists(tx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf(
+			"Playlist %s already exists",
+			name,
+		)
+	}
+	smart, err := smartPlaylistExists(tx, name)
+	if err != nil {
+		return err
+	}
+	if smart {
+		return fmt.Errorf(
+			"Smart playlist %s already exists",
+			name,
+		)
+	}
+	return nil
+}
+
+// RenamePlaylist renames the playlist and moves its musics to the new name
+func (d *Db) RenamePlaylist(oldName, newName string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkNewPlaylistName(tx, newName); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`UPDATE playlist SET name = ?, updated_at = ? WHERE name = ?`,
+		newName,
+		time.Now().Unix(),
+		oldName,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`UPDATE music_playlist SET playlist_name = ? WHERE playlist_name = ?`,
+		newName,
+		oldName,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CopyPlaylist creates the playlist dst with the description and the musics of src
+func (d *Db) CopyPlaylist(src, dst string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkNewPlaylistName(tx, dst); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO playlist (name, description, created_at, updated_at)
+     SELECT ?, description, ?, ? FROM playlist WHERE name = ?`,
+		dst,
+		time.Now().Unix(),
+		time.Now().Unix(),
+		src,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO music_playlist (music_name, playlist_name, position)
+     SELECT music_name, ?, position FROM music_playlist WHERE playlist_name = ?`,
+		dst,
+		src,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergePlaylists appends the musics of the playlists to into, in order,
+// skipping the ones already there, into is created if it doesn't exist
+func (d *Db) MergePlaylists(names []string, into string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	smart, err := smartPlaylistExists(tx, into)
+	if err != nil {
+		return err
+	}
+	if smart {
+		return fmt.Errorf(
+			"Smart playlist %s already exists",
+			into,
+		)
+	}
+	_, err = tx.Exec(
+		`INSERT OR IGNORE INTO playlist (name, created_at, updated_at) VALUES (?, ?, ?)`,
+		into,
+		time.Now().Unix(),
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return err
+	}
+
+	var size int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM music_playlist WHERE playlist_name = ?`,
+		into,
+	).Scan(&size)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		rows, err := tx.Query(
+			`SELECT music_name FROM music_playlist WHERE playlist_name = ? ORDER BY position`,
+			name,
+		)
+		if err != nil {
+			return err
+		}
+		var musics []string
+		for rows.Next() {
+			var music string
+			if err := rows.Scan(&music); err != nil {
+				rows.Close()
+				return err
+			}
+			musics = append(musics, music)
+		}
+		rows.Close()
+
+		for _, music := range musics {
+			res, err := tx.Exec(
+				`INSERT OR IGNORE INTO music_playlist (music_name, playlist_name, position) VALUES (?, ?, ?)`,
+				music,
+				into,
+				size,
+			)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				size++
+			}
+		}
+	}
+	if err := touchPlaylist(tx, into); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (d *Db) RemovePlaylist(name string) error {
 	_, err := d.db.Exec(
 		`DELETE FROM playlist WHERE name = ?`,
@@ -68,11 +311,42 @@ func (d *Db) RemovePlaylist(name string) error {
 	return err
 }
 
-func (d *Db) AddMusicToPlaylist(musicName, playlistName string) error {
-	_, err := d.db.Exec(
-		`INSERT INTO music_playlist (music_name, playlist_name) VALUES (?, ?)`,
+// EndOfPlaylist is the position that appends a music to a playlist
+const EndOfPlaylist = -1
+
+// AddMusicToPlaylist inserts the music at position in the playlist,
+// the following musics are shifted, EndOfPlaylist appends the music
+func (d *Db) AddMusicToPlaylist(musicName, playlistName string, position int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var size int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM music_playlist WHERE playlist_name = ?`,
+		playlistName,
+	).Scan(&size)
+	if err != nil {
+		return err
+	}
+	if position < 0 || position > size {
+		position = size
+	}
+	_, err = tx.Exec(
+		`UPDATE music_playlist SET position = position + 1 WHERE playlist_name = ? AND position >= ?`,
+		playlistName,
+		position,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO music_playlist (music_name, playlist_name, position) VALUES (?, ?, ?)`,
 		musicName,
 		playlistName,
+		position,
 	)
 	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
 		return fmt.Errorf(
@@ -81,58 +355,166 @@ func (d *Db) AddMusicToPlaylist(musicName, playlistName string) error {
 			playlistName,
 		)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	if err := touchPlaylist(tx, playlistName); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (d *Db) RemoveMusicFromPlaylist(
 	playlistName string,
 	musicName string,
 ) error {
-	_, err := d.db.Exec(
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+	err = tx.QueryRow(
+		`SELECT position FROM music_playlist WHERE music_name = ? AND playlist_name = ?`,
+		musicName,
+		playlistName,
+	).Scan(&position)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
 		`DELETE FROM music_playlist WHERE music_name= ? AND playlist_name = ?`,
 		musicName,
 		playlistName,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`UPDATE music_playlist SET position = position - 1 WHERE playlist_name = ? AND position > ?`,
+		playlistName,
+		position,
+	)
+	if err != nil {
+		return err
+	}
+	if err := touchPlaylist(tx, playlistName); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (d *Db) GetMusicsFromPlaylist(playlistName string) ([]Music, error) {
-	rows, err := d.db.Query(
-		`SELECT m.name, m.source, m.key, m.data
-         FROM music m
-        JOIN music_playlist mp ON m.name = mp.music_name
-         WHERE mp.playlist_name = ?`,
+// MoveMusicInPlaylist moves the music at index from to index to,
+// the musics in between are shifted
+func (d *Db) MoveMusicInPlaylist(playlistName string, from, to int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var size int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM music_playlist WHERE playlist_name = ?`,
 		playlistName,
-	)
+	).Scan(&size)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if from < 0 || from >= size || to < 0 || to >= size {
+		return fmt.Errorf("index out of range [0, %d)", size)
+	}
+	if from == to {
+		return nil
 	}
-	defer rows.Close()
 
-	var musics []Music
-	for rows.Next() {
-		var music Music
-		err := rows.Scan(
-			&music.Name,
-			&music.Source,
-			&music.Key,
-			&music.Data,
+	var musicName string
+	err = tx.QueryRow(
+		`SELECT music_name FROM music_playlist WHERE playlist_name = ? AND position = ?`,
+		playlistName,
+		from,
+	).Scan(&musicName)
+	if err != nil {
+		return err
+	}
+	if from < to {
+		_, err = tx.Exec(
+			`UPDATE music_playlist SET position = position - 1 WHERE playlist_name = ? AND position > ? AND position <= ?`,
+			playlistName,
+			from,
+			to,
 		)
-		if err != nil {
-			return nil, err
-		}
-		musics = append(musics, music)
+	} else {
+		_, err = tx.Exec(
+			`UPDATE music_playlist SET position = position + 1 WHERE playlist_name = ? AND position >= ? AND position < ?`,
+			playlistName,
+			to,
+			from,
+		)
+	}
+	if err != nil {
+		return err
 	}
+	_, err = tx.Exec(
+		`UPDATE music_playlist SET position = ? WHERE playlist_name = ? AND music_name = ?`,
+		to,
+		playlistName,
+		musicName,
+	)
+	if err != nil {
+		return err
+	}
+	if err := touchPlaylist(tx, playlistName); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	return musics, nil
+func (d *Db) GetMusicsFromPlaylist(playlistName string) ([]Music, error) {
+	rows, err := d.db.Query(
+		`SELECT `+musicColumns+`
+         FROM music
+        JOIN music_playlist mp ON music.name = mp.music_name
+         WHERE mp.playlist_name = ?
+         ORDER BY mp.position`,
+		playlistName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMusics(rows)
 }
 
 func (d *Db) InitPlaylist() error {
 	_, err := d.db.Exec(
 		`CREATE TABLE IF NOT EXISTS playlist (
-      name TEXT PRIMARY KEY
+      name TEXT PRIMARY KEY,
+      description TEXT NOT NULL DEFAULT '',
+      created_at INTEGER NOT NULL DEFAULT 0,
+      updated_at INTEGER NOT NULL DEFAULT 0
     )`,
 	)
+	if err != nil {
+		return err
+	}
+	// migrate databases created before the description and timestamps
+	columns := [][2]string{
+		{"description", `TEXT NOT NULL DEFAULT ''`},
+		{"created_at", `INTEGER NOT NULL DEFAULT 0`},
+		{"updated_at", `INTEGER NOT NULL DEFAULT 0`},
+	}
+	for _, c := range columns {
+		if err := d.addColumn("playlist", c[0], c[1]); err != nil {
+			return err
+		}
+	}
+	// the playlists created before the timestamps are dated from the migration
+	_, err = d.db.Exec(
+		`UPDATE playlist SET created_at = ?, updated_at = ? WHERE created_at = 0`,
+		time.Now().Unix(),
+		time.Now().Unix(),
+	)
 	return err
 }
 
@@ -142,10 +524,70 @@ func (d *Db) InitMusicPlaylist() error {
 		`CREATE TABLE IF NOT EXISTS music_playlist (
       music_name TEXT,
       playlist_name TEXT,
+      position INTEGER NOT NULL DEFAULT 0,
       PRIMARY KEY (music_name, playlist_name),
       FOREIGN KEY (music_name) REFERENCES music (name),
       FOREIGN KEY (playlist_name) REFERENCES playlist (name)
     )`,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	err = d.addColumn("music_playlist", "position", `INTEGER NOT NULL DEFAULT 0`)
+	if err != nil {
+		return err
+	}
+	return d.renumberPlaylists()
+}
+
+// renumberPlaylists gives distinct positions to the musics of the playlists
+// created before the position column, keeping their insertion order
+func (d *Db) renumberPlaylists() error {
+	var duplicates int
+	err := d.db.QueryRow(
+		`SELECT COUNT(*) FROM (
+      SELECT 1 FROM music_playlist GROUP BY playlist_name, position HAVING COUNT(*) > 1
+    )`,
+	).Scan(&duplicates)
+	if err != nil || duplicates == 0 {
+		return err
+	}
+	rows, err := d.db.Query(
+		`SELECT rowid, playlist_name FROM music_playlist ORDER BY playlist_name, position, rowid`,
+	)
+	if err != nil {
+		return err
+	}
+	positions := make(map[int64]int)
+	sizes := make(map[string]int)
+	for rows.Next() {
+		var (
+			rowid    int64
+			playlist string
+		)
+		if err := rows.Scan(&rowid, &playlist); err != nil {
+			rows.Close()
+			return err
+		}
+		positions[rowid] = sizes[playlist]
+		sizes[playlist]++
+	}
+	rows.Close()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for rowid, position := range positions {
+		_, err := tx.Exec(
+			`UPDATE music_playlist SET position = ? WHERE rowid = ?`,
+			position,
+			rowid,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Podcast struct {
+	Name        string
+	Url         string
+	Title       string
+	Author      string
+	Description string
+	RefreshedAt time.Time
+	Episodes    int
+}
+
+type Episode struct {
+	Guid      string
+	Title     string
+	Url       string
+	Published time.Time
+	Duration  time.Duration
+	Cached    bool // the episode is downloaded in the music table
+}
+
+func (d *Db) InitPodcast() error {
+	_, err := d.db.Exec(
+		`CREATE TABLE IF NOT EXISTS podcast (
+      name TEXT PRIMARY KEY,
+      url TEXT UNIQUE NOT NULL,
+      title TEXT NOT NULL DEFAULT '',
+      author TEXT NOT NULL DEFAULT '',
+      description TEXT NOT NULL DEFAULT '',
+      refreshed_at INTEGER NOT NULL DEFAULT 0
+    )`,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(
+		`CREATE TABLE IF NOT EXISTS podcast_episode (
+      podcast_name TEXT NOT NULL,
+      guid TEXT NOT NULL,
+      title TEXT NOT NULL DEFAULT '',
+      url TEXT NOT NULL,
+      published_at INTEGER NOT NULL DEFAULT 0,
+      duration INTEGER NOT NULL DEFAULT 0,
+      PRIMARY KEY (podcast_name, guid),
+      FOREIGN KEY (podcast_name) REFERENCES podcast (name)
+    )`,
+	)
+	return err
+}
+
+// AddPodcast stores the podcast, its episodes are saved by SaveEpisodes
+func (d *Db) AddPodcast(podcast Podcast) error {
+	_, err := d.db.Exec(
+		`INSERT INTO podcast (name, url, title, author, description) VALUES (?, ?, ?, ?, ?)`,
+		podcast.Name,
+		podcast.Url,
+		podcast.Title,
+		podcast.Author,
+		podcast.Description,
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: podcast.url") {
+		return fmt.Errorf(
+			"Podcast %s is already added",
+			podcast.Url,
+		)
+	}
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return fmt.Errorf(
+			"Podcast %s already exists",
+			podcast.Name,
+		)
+	}
+	return err
+}
+
+const podcastColumns = `podcast.name, podcast.url, podcast.title, podcast.author,
+  podcast.description, podcast.refreshed_at,
+  (SELECT COUNT(*) FROM podcast_episode WHERE podcast_name = podcast.name)`
+
+func scanPodcast(row rowScanner) (Podcast, error) {
+	var podcast Podcast
+	var refreshedAt int64
+	err := row.Scan(
+		&podcast.Name,
+		&podcast.Url,
+		&podcast.Title,
+		&podcast.Author,
+		&podcast.Description,
+		&refreshedAt,
+		&podcast.Episodes,
+	)
+	podcast.RefreshedAt = time.Unix(refreshedAt, 0)
+	return podcast, err
+}
+
+func (d *Db) GetPodcast(name string) (Podcast, error) {
+	return scanPodcast(
+		d.db.QueryRow(
+			`SELECT `+podcastColumns+` FROM podcast WHERE name = ?`,
+			name,
+		),
+	)
+}
+
+func (d *Db) GetPodcasts() ([]Podcast, error) {
+	rows, err := d.db.Query(
+		`SELECT ` + podcastColumns + ` FROM podcast ORDER BY name COLLATE NOCASE`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var podcasts []Podcast
+	for rows.Next() {
+		podcast, err := scanPodcast(rows)
+		if err != nil {
+			return nil, err
+		}
+		podcasts = append(podcasts, podcast)
+	}
+	return podcasts, rows.Err()
+}
+
+// RemovePodcast removes the podcast and its episodes,
+// the downloaded episodes stay in the cache
+func (d *Db) RemovePodcast(name string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(
+		`DELETE FROM podcast_episode WHERE podcast_name = ?`,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(
+		`DELETE FROM podcast WHERE name = ?`,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf(
+			"Podcast %s not found",
+			name,
+		)
+	}
+	return tx.Commit()
+}
+
+// SaveEpisodes adds the new episodes of the podcast and updates the known ones,
+// the feed details and the refresh date of the podcast are updated too
+func (d *Db) SaveEpisodes(podcast Podcast, episodes []Episode) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(
+		`UPDATE podcast SET title = ?, author = ?, description = ?, refreshed_at = ? WHERE name = ?`,
+		podcast.Title,
+		podcast.Author,
+		podcast.Description,
+		time.Now().Unix(),
+		podcast.Name,
+	)
+	if err != nil {
+		return err
+	}
+	for _, episode := range episodes {
+		var publishedAt int64
+		if !episode.Published.IsZero() {
+			publishedAt = episode.Published.Unix()
+		}
+		_, err := tx.Exec(
+			`INSERT INTO podcast_episode (podcast_name, guid, title, url, published_at, duration)
+      VALUES (?, ?, ?, ?, ?, ?)
+      ON CONFLICT (podcast_name, guid) DO UPDATE SET
+        title = excluded.title,
+        url = excluded.url,
+        published_at = excluded.published_at,
+        duration = excluded.duration`,
+			podcast.Name,
+			episode.Guid,
+			episode.Title,
+			episode.Url,
+			publishedAt,
+			int64(episode.Duration.Seconds()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEpisodes returns the episodes of the podcast, the newest first
+func (d *Db) GetEpisodes(podcastName string) ([]Episode, error) {
+	rows, err := d.db.Query(
+		`SELECT guid, title, url, published_at, duration,
+      EXISTS (SELECT 1 FROM music WHERE music.source = 'podcast' AND music.key = podcast_episode.url)
+    FROM podcast_episode WHERE podcast_name = ?
+    ORDER BY published_at DESC, rowid`,
+		podcastName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var episodes []Episode
+	for rows.Next() {
+		var episode Episode
+		var publishedAt, duration int64
+		err := rows.Scan(
+			&episode.Guid,
+			&episode.Title,
+			&episode.Url,
+			&publishedAt,
+			&duration,
+			&episode.Cached,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if publishedAt > 0 {
+			episode.Published = time.Unix(publishedAt, 0)
+		}
+		episode.Duration = time.Duration(duration) * time.Second
+		episodes = append(episodes, episode)
+	}
+	return episodes, rows.Err()
+}
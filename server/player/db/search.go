@@ -0,0 +1,253 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// musicInfoColumns is like musicColumns without the audio data,
+// it is used by listings that don't need to decode the music
+const musicInfoColumns = `music.name, music.source, music.key, NULL, music.hash,
+  music.title, music.artist, music.album, music.duration, music.track_number, music.year,
+  music.size, music.last_played_at, music.pinned`
+
+// InitMusicFts creates the full text index over the music table,
+// it needs sqlite to be built with the sqlite_fts5 tag
+func (d *Db) InitMusicFts() error {
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS music_fts USING fts5(
+      hash UNINDEXED,
+      name,
+      title,
+      artist,
+      album,
+      key,
+      tokenize = "unicode61 remove_diacritics 2",
+      prefix = '2 3 4'
+    )`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS music_fts_vocab USING fts5vocab(music_fts, 'row')`,
+		`CREATE TRIGGER IF NOT EXISTS music_fts_insert AFTER INSERT ON music BEGIN
+      INSERT INTO music_fts (hash, name, title, artist, album, key)
+      VALUES (new.hash, new.name, new.title, new.artist, new.album, new.key);
+    END`,
+		`CREATE TRIGGER IF NOT EXISTS music_fts_delete AFTER DELETE ON music BEGIN
+      DELETE FROM music_fts WHERE hash = old.hash;
+    END`,
+		// the first version of the trigger ran on every update, like the plays and the pins
+		`DROP TRIGGER IF EXISTS music_fts_update`,
+		`CREATE TRIGGER music_fts_update
+    AFTER UPDATE OF name, title, artist, album, key, hash ON music BEGIN
+      DELETE FROM music_fts WHERE hash = old.hash;
+      INSERT INTO music_fts (hash, name, title, artist, album, key)
+      VALUES (new.hash, new.name, new.title, new.artist, new.album, new.key);
+    END`,
+	}
+	for _, statement := range statements {
+		if _, err := d.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	// index the musics added before the fts table existed
+	var outdated bool
+	err := d.db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM music) != (SELECT COUNT(*) FROM music_fts)`,
+	).Scan(&outdated)
+	if err != nil || !outdated {
+		return err
+	}
+	_, err = d.db.Exec(`DELETE FROM music_fts`)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(
+		`INSERT INTO music_fts (hash, name, title, artist, album, key)
+     SELECT hash, name, title, artist, album, key FROM music`,
+	)
+	return err
+}
+
+// FtsEnabled reports whether the full text index is available
+func (d *Db) FtsEnabled() bool {
+	return d.fts
+}
+
+// SearchMusic returns the musics matching the query ranked by relevance,
+// every word of the query is matched as a prefix, and words that match
+// nothing are replaced by the closest words of the library
+func (d *Db) SearchMusic(query string, limit int) ([]Music, error) {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	if !d.fts {
+		return d.likeSearchMusic(words, limit)
+	}
+
+	groups := make([]string, 0, len(words))
+	for _, word := range words {
+		terms := []string{fmt.Sprintf(`"%s"*`, word)}
+		found, err := d.hasTermPrefix(word)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			similar, err := d.similarTerms(word)
+			if err != nil {
+				return nil, err
+			}
+			for _, term := range similar {
+				terms = append(terms, fmt.Sprintf(`"%s"`, term))
+			}
+		}
+		groups = append(groups, "("+strings.Join(terms, " OR ")+")")
+	}
+
+	musics, err := d.matchMusic(strings.Join(groups, " AND "), limit)
+	if err != nil || len(musics) > 0 || len(groups) == 1 {
+		return musics, err
+	}
+	// nothing matches every word, rank the musics matching any of them
+	return d.matchMusic(strings.Join(groups, " OR "), limit)
+}
+
+// FindMusicByMeta returns the music with the title and, when known, the artist,
+// the one from the same album is preferred
+func (d *Db) FindMusicByMeta(title, artist, album string) (Music, error) {
+	return scanMusic(
+		d.db.QueryRow(
+			`SELECT `+musicColumns+` FROM music
+       WHERE (title = ?1 COLLATE NOCASE OR name = ?1 COLLATE NOCASE)
+       AND (?2 = '' OR artist = ?2 COLLATE NOCASE)
+       ORDER BY album = ?3 COLLATE NOCASE DESC
+       LIMIT 1`,
+			title,
+			artist,
+			album,
+		),
+	)
+}
+
+func (d *Db) matchMusic(match string, limit int) ([]Music, error) {
+	rows, err := d.db.Query(
+		`SELECT `+musicInfoColumns+`
+     FROM music_fts
+     JOIN music ON music.hash = music_fts.hash
+     WHERE music_fts MATCH ?
+     ORDER BY bm25(music_fts, 0, 4.0, 10.0, 5.0, 3.0, 1.0)
+     LIMIT ?`,
+		match,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMusics(rows)
+}
+
+// likeSearchMusic is used when sqlite is built without fts5
+func (d *Db) likeSearchMusic(words []string, limit int) ([]Music, error) {
+	var conditions []string
+	var args []any
+	for _, word := range words {
+		conditions = append(
+			conditions,
+			`(name LIKE ? OR title LIKE ? OR artist LIKE ? OR album LIKE ?)`,
+		)
+		pattern := "%" + word + "%"
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+	args = append(args, limit)
+	rows, err := d.db.Query(
+		`SELECT `+musicInfoColumns+` FROM music WHERE `+strings.Join(conditions, " AND ")+` LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMusics(rows)
+}
+
+func (d *Db) hasTermPrefix(word string) (bool, error) {
+	var count int
+	err := d.db.QueryRow(
+		`SELECT COUNT(*) FROM music_fts_vocab WHERE term >= ? AND term < ?`,
+		word,
+		word+"\uffff",
+	).Scan(&count)
+	return count > 0, err
+}
+
+// similarTerms returns the indexed words close to word by edit distance
+func (d *Db) similarTerms(word string) ([]string, error) {
+	maxDistance := 1
+	if len([]rune(word)) <= 3 {
+		return nil, nil
+	}
+	if len([]rune(word)) > 6 {
+		maxDistance = 2
+	}
+
+	rows, err := d.db.Query(`SELECT term FROM music_fts_vocab`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		term     string
+		distance int
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		if dist := levenshtein(word, term); dist <= maxDistance {
+			candidates = append(candidates, candidate{term, dist})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var terms []string
+	for i := 0; i < len(candidates) && i < 5; i++ {
+		terms = append(terms, candidates[i].term)
+	}
+	return terms, rows.Err()
+}
+
+// searchWords splits the query the same way the unicode61 tokenizer does
+func searchWords(query string) []string {
+	return strings.FieldsFunc(
+		strings.ToLower(query),
+		func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		},
+	)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,523 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// smart playlists are stored as a rule over the music table
+// and evaluated every time they are played, the rule language is:
+//
+//	rule      := or
+//	or        := and ("OR" and)*
+//	and       := not ("AND" not)*
+//	not       := "NOT" not | "(" rule ")" | condition
+//	condition := field op value | field "CONTAINS" value | field "IN LAST" number unit
+//
+// e.g. `source = youtube AND play_count > 5`, `added in last 30 days`, `artist = "Daft Punk"`
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	numberField
+	boolField
+	timeField
+)
+
+type smartField struct {
+	column string
+	kind   fieldKind
+}
+
+var smartFields = map[string]smartField{
+	"name":         {`music.name`, textField},
+	"title":        {`music.title`, textField},
+	"artist":       {`music.artist`, textField},
+	"album":        {`music.album`, textField},
+	"source":       {`music.source`, textField},
+	"year":         {`music.year`, numberField},
+	"track":        {`music.track_number`, numberField},
+	"track_number": {`music.track_number`, numberField},
+	"duration":     {`music.duration`, numberField}, // seconds
+	"size":         {`music.size`, numberField},     // bytes
+	"play_count":   {`music.play_count`, numberField},
+	"plays":        {`music.play_count`, numberField},
+	"pinned":       {`music.pinned`, boolField},
+	"added":        {`music.added_at`, timeField},
+	"played":       {`music.last_played_at`, timeField},
+}
+
+var smartUnits = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+var smartOperators = []string{"!=", "<>", "<=", ">=", "=", "<", ">", "~"}
+
+type smartToken struct {
+	text   string
+	quoted bool
+	op     bool // operator or parenthesis
+}
+
+// keyword tells if the token is the unquoted keyword, case insensitive
+func (t smartToken) keyword(k string) bool {
+	return !t.quoted && strings.EqualFold(t.text, k)
+}
+
+func tokenizeRule(rule string) ([]smartToken, error) {
+	var tokens []smartToken
+	runes := []rune(rule)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, smartToken{text: string(r), op: true})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, smartToken{text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		default:
+			op := ""
+			for _, o := range smartOperators {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, smartToken{text: op, op: true})
+				i += len([]rune(op))
+				continue
+			}
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) &&
+				!strings.ContainsRune(`()"'=!<>~`, runes[end]) {
+				end++
+			}
+			if end == i {
+				// a lone character like "!"
+				end++
+			}
+			tokens = append(tokens, smartToken{text: string(runes[i:end])})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type ruleParser struct {
+	tokens []smartToken
+	pos    int
+	values []any
+	now    time.Time
+}
+
+func (p *ruleParser) peek() (smartToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return smartToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *ruleParser) next() (smartToken, error) {
+	t, ok := p.peek()
+	if !ok {
+		return t, fmt.Errorf("unexpected end of rule")
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *ruleParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !t.keyword("OR") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " OR " + right + ")"
+	}
+}
+
+func (p *ruleParser) parseAnd() (string, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return "", err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !t.keyword("AND") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " AND " + right + ")"
+	}
+}
+
+func (p *ruleParser) parseNot() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case t.keyword("NOT"):
+		cond, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + cond, nil
+	case t.op && t.text == "(":
+		cond, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		closing, err := p.next()
+		if err != nil || !closing.op || closing.text != ")" {
+			return "", fmt.Errorf("missing )")
+		}
+		return "(" + cond + ")", nil
+	default:
+		return p.parseCondition(t)
+	}
+}
+
+// parseValue reads a quoted string or the words up to the next keyword
+func (p *ruleParser) parseValue() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if t.quoted {
+		return t.text, nil
+	}
+	if t.op || t.keyword("AND") || t.keyword("OR") {
+		return "", fmt.Errorf("expected a value, got %q", t.text)
+	}
+	words := []string{t.text}
+	for {
+		t, ok := p.peek()
+		if !ok || t.quoted || t.op || t.keyword("AND") || t.keyword("OR") {
+			return strings.Join(words, " "), nil
+		}
+		words = append(words, t.text)
+		p.pos++
+	}
+}
+
+func (p *ruleParser) parseCondition(name smartToken) (string, error) {
+	field, ok := smartFields[strings.ToLower(name.text)]
+	if name.quoted || !ok {
+		return "", fmt.Errorf("unknown field %q", name.text)
+	}
+	op, err := p.next()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case op.keyword("IN"):
+		return p.parseInLast(name.text, field)
+	case op.keyword("CONTAINS") || (op.op && op.text == "~"):
+		if field.kind != textField {
+			return "", fmt.Errorf("%s is not a text field", name.text)
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return "", err
+		}
+		p.values = append(p.values, "%"+value+"%")
+		return field.column + ` LIKE ?`, nil
+	}
+
+	sqlOp := op.text
+	if sqlOp == "<>" {
+		sqlOp = "!="
+	}
+	switch {
+	case !op.op:
+		return "", fmt.Errorf("expected an operator after %s, got %q", name.text, op.text)
+	case sqlOp == "=", sqlOp == "!=", sqlOp == "<", sqlOp == "<=", sqlOp == ">", sqlOp == ">=":
+	default:
+		return "", fmt.Errorf("unknown operator %q after %s", op.text, name.text)
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return "", err
+	}
+
+	switch field.kind {
+	case textField:
+		if sqlOp != "=" && sqlOp != "!=" {
+			return "", fmt.Errorf("%s only supports =, != and contains", name.text)
+		}
+		p.values = append(p.values, value)
+		return field.column + ` ` + sqlOp + ` ? COLLATE NOCASE`, nil
+	case boolField:
+		b, err := strconv.ParseBool(value)
+		if err != nil || (sqlOp != "=" && sqlOp != "!=") {
+			return "", fmt.Errorf("%s only supports = true and = false", name.text)
+		}
+		p.values = append(p.values, b)
+	case timeField:
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return "", fmt.Errorf("%s expects a date like 2006-01-02 or \"in last <n> <unit>\"", name.text)
+		}
+		p.values = append(p.values, date.Unix())
+		return timeCondition(field, sqlOp), nil
+	default:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s expects a number, got %q", name.text, value)
+		}
+		p.values = append(p.values, n)
+	}
+	return field.column + ` ` + sqlOp + ` ?`, nil
+}
+
+// parseInLast parses "in last <n> <unit>", the "in" is already read
+func (p *ruleParser) parseInLast(name string, field smartField) (string, error) {
+	if field.kind != timeField {
+		return "", fmt.Errorf("%s is not a date field", name)
+	}
+	last, err := p.next()
+	if err != nil || !last.keyword("LAST") {
+		return "", fmt.Errorf("expected \"in last <n> <unit>\" after %s", name)
+	}
+	count, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(count.text)
+	if err != nil {
+		return "", fmt.Errorf("expected a number after \"in last\", got %q", count.text)
+	}
+	unit, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	d, ok := smartUnits[strings.TrimSuffix(strings.ToLower(unit.text), "s")]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", unit.text)
+	}
+	p.values = append(p.values, p.now.Add(-time.Duration(n)*d).Unix())
+	return timeCondition(field, ">="), nil
+}
+
+// timeCondition compares a date column, 0 is an unknown date like the adding
+// date of the musics cached before it was recorded, it never matches
+func timeCondition(field smartField, sqlOp string) string {
+	return `(` + field.column + ` != 0 AND ` + field.column + ` ` + sqlOp + ` ?)`
+}
+
+// CompileSmartRule turns a smart playlist rule into a WHERE condition and its values,
+// the relative dates are computed from now
+func CompileSmartRule(rule string, now time.Time) (string, []any, error) {
+	tokens, err := tokenizeRule(rule)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(tokens) == 0 {
+		return "", nil, fmt.Errorf("empty rule")
+	}
+	p := &ruleParser{
+		tokens: tokens,
+		now:    now,
+	}
+	where, err := p.parseOr()
+	if err != nil {
+		return "", nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return "", nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return where, p.values, nil
+}
+
+type SmartPlaylist struct {
+	Name      string
+	Rule      string
+	CreatedAt time.Time
+}
+
+func (d *Db) InitSmartPlaylist() error {
+	_, err := d.db.Exec(
+		`CREATE TABLE IF NOT EXISTS smart_playlist (
+      name TEXT PRIMARY KEY,
+      rule TEXT NOT NULL,
+      created_at INTEGER NOT NULL DEFAULT 0
+    )`,
+	)
+	return err
+}
+
+// smartPlaylistExists tells if a smart playlist has the name, the playlists
+// and the smart playlists share their names
+func smartPlaylistExists(tx *sql.Tx, name string) (bool, error) {
+	var count int
+	err := tx.QueryRow(
+		`SELECT COUNT(*) FROM smart_playlist WHERE name = ?`,
+		name,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// AddSmartPlaylist stores the smart playlist, the rule is checked first,
+// a playlist can't have its name
+func (d *Db) AddSmartPlaylist(name, rule string) error {
+	if _, _, err := CompileSmartRule(rule, time.Now()); err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exists, err := playlistExists(tx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf(
+			"Playlist %s already exists",
+			name,
+		)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO smart_playlist (name, rule, created_at) VALUES (?, ?, ?)`,
+		name,
+		rule,
+		time.Now().Unix(),
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return fmt.Errorf(
+			"Smart playlist %s already exists",
+			name,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *Db) GetSmartPlaylist(name string) (SmartPlaylist, error) {
+	var playlist SmartPlaylist
+	var createdAt int64
+	err := d.db.QueryRow(
+		`SELECT name, rule, created_at FROM smart_playlist WHERE name = ?`,
+		name,
+	).Scan(
+		&playlist.Name,
+		&playlist.Rule,
+		&createdAt,
+	)
+	playlist.CreatedAt = time.Unix(createdAt, 0)
+	return playlist, err
+}
+
+func (d *Db) GetSmartPlaylists() ([]SmartPlaylist, error) {
+	rows, err := d.db.Query(
+		`SELECT name, rule, created_at FROM smart_playlist ORDER BY created_at, rowid`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var playlists []SmartPlaylist
+	for rows.Next() {
+		var playlist SmartPlaylist
+		var createdAt int64
+		err := rows.Scan(
+			&playlist.Name,
+			&playlist.Rule,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		playlist.CreatedAt = time.Unix(createdAt, 0)
+		playlists = append(playlists, playlist)
+	}
+	return playlists, rows.Err()
+}
+
+func (d *Db) RemoveSmartPlaylist(name string) error {
+	_, err := d.db.Exec(
+		`DELETE FROM smart_playlist WHERE name = ?`,
+		name,
+	)
+	return err
+}
+
+// GetSmartPlaylistMusics evaluates the rule of the smart playlist
+func (d *Db) GetSmartPlaylistMusics(name string) ([]Music, error) {
+	playlist, err := d.GetSmartPlaylist(name)
+	if err != nil {
+		return nil, err
+	}
+	where, values, err := CompileSmartRule(playlist.Rule, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.db.Query(
+		`SELECT `+musicColumns+` FROM music WHERE `+where+`
+     ORDER BY music.artist COLLATE NOCASE, music.album COLLATE NOCASE, music.track_number, music.title COLLATE NOCASE`,
+		values...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMusics(rows)
+}
+
+// CountSmartPlaylistMusics returns the number of musics matching the rule
+// of the smart playlist without reading them
+func (d *Db) CountSmartPlaylistMusics(name string) (int, error) {
+	playlist, err := d.GetSmartPlaylist(name)
+	if err != nil {
+		return 0, err
+	}
+	where, values, err := CompileSmartRule(playlist.Rule, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = d.db.QueryRow(
+		`SELECT COUNT(*) FROM music WHERE `+where,
+		values...,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileSmartRule(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule   string
+		where  string
+		values []any
+	}{
+		// fields and operators
+		{
+			rule:   `source = youtube`,
+			where:  `music.source = ? COLLATE NOCASE`,
+			values: []any{"youtube"},
+		},
+		{
+			rule:   `Artist <> "Daft Punk"`,
+			where:  `music.artist != ? COLLATE NOCASE`,
+			values: []any{"Daft Punk"},
+		},
+		{
+			rule:   `plays>=5`,
+			where:  `music.play_count >= ?`,
+			values: []any{int64(5)},
+		},
+		{
+			rule:   `pinned = true`,
+			where:  `music.pinned = ?`,
+			values: []any{true},
+		},
+		{
+			rule:   `title contains love`,
+			where:  `music.title LIKE ?`,
+			values: []any{"%love%"},
+		},
+		{
+			rule:   `album ~ greatest hits`,
+			where:  `music.album LIKE ?`,
+			values: []any{"%greatest hits%"},
+		},
+		{
+			rule:   `added in last 30 days`,
+			where:  `(music.added_at != 0 AND music.added_at >= ?)`,
+			values: []any{now.Add(-30 * 24 * time.Hour).Unix()},
+		},
+		{
+			rule:   `played IN LAST 1 week`,
+			where:  `(music.last_played_at != 0 AND music.last_played_at >= ?)`,
+			values: []any{now.Add(-7 * 24 * time.Hour).Unix()},
+		},
+		{
+			rule:   `added < 2024-01-15`,
+			where:  `(music.added_at != 0 AND music.added_at < ?)`,
+			values: []any{time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local).Unix()},
+		},
+
+		// precedence
+		{
+			rule:   `source = youtube OR source = file AND plays > 5`,
+			where:  `(music.source = ? COLLATE NOCASE OR (music.source = ? COLLATE NOCASE AND music.play_count > ?))`,
+			values: []any{"youtube", "file", int64(5)},
+		},
+		{
+			rule:   `year >= 1980 and year < 1990 or pinned = true`,
+			where:  `((music.year >= ? AND music.year < ?) OR music.pinned = ?)`,
+			values: []any{int64(1980), int64(1990), true},
+		},
+		{
+			rule:   `NOT source = youtube AND plays = 0`,
+			where:  `(NOT music.source = ? COLLATE NOCASE AND music.play_count = ?)`,
+			values: []any{"youtube", int64(0)},
+		},
+		{
+			rule:   `not not pinned = false`,
+			where:  `NOT NOT music.pinned = ?`,
+			values: []any{false},
+		},
+
+		// parentheses
+		{
+			rule:   `(source = youtube OR source = file) AND plays > 5`,
+			where:  `(((music.source = ? COLLATE NOCASE OR music.source = ? COLLATE NOCASE)) AND music.play_count > ?)`,
+			values: []any{"youtube", "file", int64(5)},
+		},
+		{
+			rule:   `NOT (artist = a OR ((artist = b)))`,
+			where:  `NOT ((music.artist = ? COLLATE NOCASE OR ((music.artist = ? COLLATE NOCASE))))`,
+			values: []any{"a", "b"},
+		},
+
+		// quoting
+		{
+			rule:   `artist = 'Guns N Roses' AND title = "Don't Cry"`,
+			where:  `(music.artist = ? COLLATE NOCASE AND music.title = ? COLLATE NOCASE)`,
+			values: []any{"Guns N Roses", "Don't Cry"},
+		},
+		{
+			rule:   `title = "a AND b OR (c)"`,
+			where:  `music.title = ? COLLATE NOCASE`,
+			values: []any{"a AND b OR (c)"},
+		},
+		{
+			rule:   `artist = Daft Punk AND album = Discovery`,
+			where:  `(music.artist = ? COLLATE NOCASE AND music.album = ? COLLATE NOCASE)`,
+			values: []any{"Daft Punk", "Discovery"},
+		},
+		{
+			rule:   `title contains "100%"`,
+			where:  `music.title LIKE ?`,
+			values: []any{"%100%%"},
+		},
+		{
+			rule:   `name = ""`,
+			where:  `music.name = ? COLLATE NOCASE`,
+			values: []any{""},
+		},
+	}
+	for _, test := range tests {
+		where, values, err := CompileSmartRule(test.rule, now)
+		if err != nil {
+			t.Errorf("CompileSmartRule(%q) failed: %v", test.rule, err)
+			continue
+		}
+		if where != test.where {
+			t.Errorf("CompileSmartRule(%q) where = %s, want %s", test.rule, where, test.where)
+		}
+		if !reflect.DeepEqual(values, test.values) {
+			t.Errorf("CompileSmartRule(%q) values = %#v, want %#v", test.rule, values, test.values)
+		}
+	}
+}
+
+func TestCompileSmartRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		// unknown fields
+		{`genre = rock`, `unknown field "genre"`},
+		{`"source" = youtube`, `unknown field "source"`},
+		{`rating > 3 AND plays > 1`, `unknown field "rating"`},
+
+		// malformed
+		{``, `empty rule`},
+		{`   `, `empty rule`},
+		{`source`, `unexpected end of rule`},
+		{`source =`, `unexpected end of rule`},
+		{`source = youtube AND`, `unexpected end of rule`},
+		{`source = youtube youtube)`, `unexpected ")"`},
+		{`(source = youtube`, `missing )`},
+		{`source = youtube)`, `unexpected ")"`},
+		{`()`, `unknown field ")"`},
+		{`source = "youtube`, `unterminated string`},
+		{`source youtube`, `expected an operator after source`},
+		{`source ! youtube`, `expected an operator after source`},
+		{`source = AND`, `expected a value, got "AND"`},
+		{`source = (youtube)`, `expected a value, got "("`},
+
+		// values and operators of the fields
+		{`source > youtube`, `source only supports =, != and contains`},
+		{`plays contains 5`, `plays is not a text field`},
+		{`plays = many`, `plays expects a number, got "many"`},
+		{`pinned = maybe`, `pinned only supports = true and = false`},
+		{`pinned > true`, `pinned only supports = true and = false`},
+		{`added = yesterday`, `added expects a date like 2006-01-02`},
+		{`plays in last 3 days`, `plays is not a date field`},
+		{`added in 3 days`, `expected "in last <n> <unit>" after added`},
+		{`added in last few days`, `expected a number after "in last", got "few"`},
+		{`added in last 3 fortnights`, `unknown unit "fortnights"`},
+		{`added in last 3`, `unexpected end of rule`},
+	}
+	for _, test := range tests {
+		where, values, err := CompileSmartRule(test.rule, time.Now())
+		if err == nil {
+			t.Errorf("CompileSmartRule(%q) = %s %v, want an error", test.rule, where, values)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("CompileSmartRule(%q) error = %q, want %q", test.rule, err, test.err)
+		}
+	}
+}
+
+func TestCountSmartPlaylistMusics(t *testing.T) {
+	d, err := LoadDb(filepath.Join(t.TempDir(), "retro.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []Music{
+		{Name: "blue_monday", Year: 1983},
+		{Name: "one_more_time", Year: 2000},
+		{Name: "take_on_me", Year: 1985},
+	} {
+		m.Source = "file"
+		m.Key = "/music/" + m.Name + ".mp3"
+		m.Data = []byte("data of " + m.Name)
+		if err := d.AddMusic(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.AddSmartPlaylist("eighties", "year >= 1980 AND year < 1990"); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := d.CountSmartPlaylistMusics("eighties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := d.GetSmartPlaylistMusics("eighties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || len(ms) != count {
+		t.Errorf("counted %d musics, got %d, want 2", count, len(ms))
+	}
+	if _, err := d.CountSmartPlaylistMusics("nineties"); err == nil {
+		t.Error("counted the musics of a missing smart playlist")
+	}
+}
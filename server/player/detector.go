@@ -7,14 +7,17 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
-	"time"
 
 	"github.com/Malwarize/retro/config"
 	"github.com/Malwarize/retro/logger"
 	"github.com/Malwarize/retro/server/player/db"
+	en "github.com/Malwarize/retro/server/player/engines"
 	"github.com/Malwarize/retro/shared"
 )
 
+// librarySearchLimit is the max number of cached musics returned by a search
+const librarySearchLimit = 20
+
 func (p *Player) CheckWhatIsThis(unknown string) DResults {
 	// check if its a dir or file
 	// TODO: check if its local path
@@ -103,6 +106,13 @@ func (p *Player) CheckWhatIsThis(unknown string) DResults {
 	if err == nil {
 		return DPlaylist
 	}
+	// the smart playlists are played like the others
+	_, err = p.Director.Db.GetSmartPlaylist(
+		unknown,
+	)
+	if err == nil {
+		return DPlaylist
+	}
 	i, err := strconv.Atoi(unknown)
 	if err != nil {
 		ok := p.Queue.GetMusicByName(
@@ -117,19 +127,33 @@ func (p *Player) CheckWhatIsThis(unknown string) DResults {
 		}
 	}
 
-	engines := p.Director.GetEngines()
-	for _, engine := range engines {
-		ok, _ := engine.Exists(unknown)
+	// a cached url is played without the network
+	if engine, ok := p.Director.CachedEngine(unknown); ok {
+		return DResults(engine)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+	defer cancel()
+	if p.Director.IsPlaylist(ctx, unknown) {
+		return DRemotePlaylist
+	}
+	for _, engine := range p.Director.EnginesWith(en.CanDownload) {
+		ok, _ := engine.Exists(ctx, unknown)
 		if ok {
 			return DResults(engine.Name())
 		}
 	}
+	if p.Director.IsLive(ctx, unknown) {
+		return DLive
+	}
 	return DUnknown
 }
 
+// searchWorker sends the results of the engine to musicChan,
+// it gives up sending when ctx is done so it never blocks after the search returned
 func (p *Player) searchWorker(
+	ctx context.Context,
 	engine string,
-	unknown string,
+	args shared.SearchArgs,
 	musicChan chan shared.SearchResult,
 	wg *sync.WaitGroup,
 ) {
@@ -137,25 +161,139 @@ func (p *Player) searchWorker(
 		logger.LogInfo(
 			"Search worker done for",
 			engine,
-			unknown,
+			args.Query,
 		)
 		wg.Done()
 	}()
 
 	searchRes, err := p.Director.Search(
+		ctx,
 		engine,
-		unknown,
+		args.Query,
+		args.Offset,
+		args.Limit,
 	)
 	if err != nil {
-		logger.LogWarn("Failed to search for", unknown, ":", err)
+		logger.LogWarn("Failed to search for", args.Query, ":", err)
 	}
 
 	for _, music := range searchRes {
-		musicChan <- music
+		select {
+		case musicChan <- music:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
-func (p *Player) GetAvailableMusicOptions(unknown string) []shared.SearchResult {
+// SearchLibrary returns a page of the cached musics matching the query, best match first,
+// a zero limit is librarySearchLimit
+func (p *Player) SearchLibrary(query string, offset, limit int) []shared.SearchResult {
+	if limit <= 0 {
+		limit = librarySearchLimit
+	}
+	offset = max(offset, 0)
+	// the matches are ranked, the page is cut from the best ones
+	ms, err := p.Director.Db.SearchMusic(
+		query,
+		offset+limit,
+	)
+	if err != nil {
+		logger.LogWarn(
+			"Failed to search library for",
+			query,
+			err,
+		)
+		return nil
+	}
+	if offset >= len(ms) {
+		return nil
+	}
+	ms = ms[offset:]
+
+	var results []shared.SearchResult
+	for _, m := range ms {
+		dur := m.Duration
+		if dur == 0 {
+			// musics cached before the duration column was added
+			full, err := p.Director.Db.GetMusicByHash(m.Hash)
+			if err != nil {
+				continue
+			}
+			music, err := NewMusicFromDb(full)
+			if err != nil {
+				logger.LogWarn(
+					"skipping music",
+					err,
+				)
+				continue
+			}
+			p.concernSpeakerLock(
+				func() {
+					dur = music.DurationD()
+				},
+			)
+		}
+		results = append(results, shared.SearchResult{
+			Title:       m.Name,
+			Destination: m.Key,
+			Duration:    dur,
+			Type:        "cache",
+			Artist:      m.Artist,
+			Album:       m.Album,
+		})
+	}
+	return results
+}
+
+// Search returns a page of the search results without detecting the query type,
+// local or the cache source restrict the search to the library
+func (p *Player) Search(args shared.SearchArgs) []shared.SearchResult {
+	switch {
+	case args.Local || args.Source == string(DCache):
+		return p.SearchLibrary(args.Query, args.Offset, args.Limit)
+	case args.Source != "":
+		return p.SearchEngine(args)
+	}
+	return p.GetAvailableMusicOptions(args)
+}
+
+// SearchSources returns the sources the queries can be searched in, the library
+// first then the engines by priority
+func (p *Player) SearchSources() []string {
+	sources := []string{string(DCache)}
+	for _, engine := range p.Director.EnginesWith(en.CanSearch) {
+		sources = append(sources, engine.Name())
+	}
+	return sources
+}
+
+// SearchEngine returns a page of the results of the engine of args.Source,
+// the clients search the sources one by one to show the results as they come
+func (p *Player) SearchEngine(args shared.SearchArgs) []shared.SearchResult {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+	defer cancel()
+	results, err := p.Director.Search(
+		ctx,
+		args.Source,
+		args.Query,
+		args.Offset,
+		args.Limit,
+	)
+	if err != nil {
+		logger.LogWarn(
+			"Failed to search for",
+			args.Query,
+			"in",
+			args.Source,
+			err,
+		)
+	}
+	return results
+}
+
+func (p *Player) GetAvailableMusicOptions(args shared.SearchArgs) []shared.SearchResult {
+	unknown := args.Query
 	// add task : this task displayed in the status: if the task is done, it will be removed
 	p.addTask(
 		unknown,
@@ -166,13 +304,15 @@ func (p *Player) GetAvailableMusicOptions(unknown string) []shared.SearchResult
 		chan shared.SearchResult,
 	)
 	var musics []shared.SearchResult
+	// canceling ctx kills the engines processes and releases the workers
 	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
 	defer cancel()
-	for name := range p.Director.GetEngines() {
+	for _, engine := range p.Director.EnginesWith(en.CanSearch) {
 		wg.Add(1)
 		go p.searchWorker(
-			name,
-			unknown,
+			ctx,
+			engine.Name(),
+			args,
 			musicChan,
 			wg,
 		)
@@ -182,36 +322,11 @@ func (p *Player) GetAvailableMusicOptions(unknown string) []shared.SearchResult
 	go func() {
 		// Get cached music
 		defer wg.Done()
-		ms, err := p.Director.Db.FilterMusic(
-			unknown,
-		)
-		if err != nil {
-			return
-		}
-
-		for _, m := range ms {
-			music, err := NewMusic(
-				m.Name,
-				m.Data,
-			)
-			if err != nil {
-				logger.LogWarn(
-					"skipping music",
-					err,
-				)
-				continue
-			}
-			var dur time.Duration
-			p.concernSpeakerLock(
-				func() {
-					dur = music.DurationD()
-				},
-			)
-			musicChan <- shared.SearchResult{
-				Title:       m.Name,
-				Destination: m.Key,
-				Duration:    dur,
-				Type:        "cache",
+		for _, music := range p.SearchLibrary(unknown, args.Offset, args.Limit) {
+			select {
+			case musicChan <- music:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -245,37 +360,52 @@ func (p *Player) GetAvailableMusicOptions(unknown string) []shared.SearchResult
 	}
 }
 
-func (p *Player) DetectAndAddToPlayList(
-	plname string,
-	unknown string,
-) ([]shared.SearchResult, error) {
+// playlistAdder returns a callback adding musics to the playlist from position,
+// each added music goes after the previous one
+func (p *Player) playlistAdder(plname string, position int) callback {
+	var mu sync.Mutex
+	return func(m db.Music) error {
+		mu.Lock()
+		defer mu.Unlock()
+		err := p.Director.Db.AddMusicToPlaylist(
+			m.Name,
+			plname,
+			position,
+		)
+		if err == nil && position != db.EndOfPlaylist {
+			position++
+		}
+		return err
+	}
+}
+
+// DetectAndAddToPlayList inserts the music at position in the playlist,
+// db.EndOfPlaylist appends it
+func (p *Player) DetectAndAddToPlayList(args shared.AddToPlayListArgs) (shared.DetectReply, error) {
+	unknown := args.Query
 	whatIsThis := p.CheckWhatIsThis(
 		unknown,
 	)
 	pl, err := p.Director.Db.GetPlaylist(
-		plname,
+		args.PlayListName,
 	)
 	if err != nil {
-		return nil, logger.LogError(
+		return shared.DetectReply{}, logger.LogError(
 			logger.GError(
 				"Playlist does not exist",
 			),
 		)
 	}
+	addToPlaylist := p.playlistAdder(pl.Name, args.Position)
 	switch whatIsThis {
 	case DDir:
 		logger.LogInfo(
 			"detected dir for",
 			unknown,
 		)
-		return nil, p.AddMusicsFromDir(
+		return shared.DetectReply{}, p.AddMusicsFromDir(
 			unknown,
-			func(m db.Music) error {
-				return p.Director.Db.AddMusicToPlaylist(
-					m.Name,
-					pl.Name,
-				)
-			},
+			addToPlaylist,
 		)
 	case DFile:
 		logger.LogInfo(
@@ -284,15 +414,10 @@ func (p *Player) DetectAndAddToPlayList(
 		)
 		err := p.AddMusicFromFile(
 			unknown,
-			func(m db.Music) error {
-				return p.Director.Db.AddMusicToPlaylist(
-					m.Name,
-					pl.Name,
-				)
-			},
+			addToPlaylist,
 		)
 		if err != nil {
-			return nil, logger.LogError(
+			return shared.DetectReply{}, logger.LogError(
 				logger.GError(
 					"Failed to add to playlist",
 					err,
@@ -315,30 +440,94 @@ func (p *Player) DetectAndAddToPlayList(
 				unknown,
 			)
 		}
-		return nil, p.Director.Db.AddMusicToPlaylist(
+		if m == nil {
+			return shared.DetectReply{}, logger.LogError(
+				logger.GError(
+					"Music not found in queue",
+				),
+			)
+		}
+		// the live streams and the musics still downloading aren't cached
+		if m.IsLive() || m.IsDownloading() {
+			return shared.DetectReply{}, logger.LogError(
+				logger.GError(
+					"Music is not in the library, it can't be added to a playlist",
+				),
+			)
+		}
+		cached, err := p.Director.Db.GetMusicByName(
 			m.Name,
-			pl.Name,
+		)
+		if err != nil {
+			return shared.DetectReply{}, logger.LogError(
+				logger.GError(
+					"Music is not in the library, it can't be added to a playlist",
+					err,
+				),
+			)
+		}
+		return shared.DetectReply{}, addToPlaylist(
+			cached,
 		)
 	case DCache:
 		logger.LogInfo(
 			"Detected cache",
 			unknown,
 		)
-		return nil, p.AddMusicFromHash(
+		return shared.DetectReply{}, p.AddMusicFromHash(
 			unknown,
-			func(m db.Music) error {
-				return p.Director.Db.AddMusicToPlaylist(
+			addToPlaylist,
+		)
+	case DPlaylist:
+		logger.LogInfo(
+			"Detected playlist",
+			unknown,
+		)
+		ms, err := p.playlistMusics(
+			unknown,
+		)
+		if err != nil {
+			return shared.DetectReply{}, err
+		}
+		for _, m := range ms {
+			if err := addToPlaylist(m); err != nil {
+				logger.LogWarn(
+					"skipping music",
 					m.Name,
-					plname,
+					"because of error",
+					err,
 				)
-			},
+			}
+		}
+	case DRemotePlaylist:
+		logger.LogInfo(
+			"Detected remote playlist",
+			unknown,
+		)
+		return shared.DetectReply{}, p.AddMusicsFromPlaylistURL(
+			unknown,
+			addToPlaylist,
+		)
+	case DLive:
+		return shared.DetectReply{}, logger.LogError(
+			logger.GError(
+				"Live streams can't be added to a playlist",
+			),
 		)
 	case DUnknown:
 		logger.LogInfo(
 			"Detected unknown",
 			unknown,
 		)
-		return p.GetAvailableMusicOptions(unknown), nil
+		return shared.DetectReply{
+			Searched: true,
+			Results: p.Search(shared.SearchArgs{
+				Query:  unknown,
+				Source: args.Source,
+				Offset: args.Offset,
+				Limit:  args.Limit,
+			}),
+		}, nil
 	default:
 		logger.LogInfo(
 			"Detected Engine",
@@ -347,31 +536,25 @@ func (p *Player) DetectAndAddToPlayList(
 		go p.AddMusicFromOnline(
 			unknown,
 			string(whatIsThis),
-			func(m db.Music) error {
-				return p.Director.Db.AddMusicToPlaylist(
-					m.Name,
-					pl.Name,
-				)
-			},
+			addToPlaylist,
 		)
 	}
-	return []shared.SearchResult{}, nil
+	return shared.DetectReply{}, nil
 }
 
-// DetectAndPlay if result is empty, it means it detects and plays the music if succeed other wise it returns the search results
-func (p *Player) DetectAndPlay(unknown string) ([]shared.SearchResult, error) {
+// DetectAndPlay plays the detected query, the other queries are searched and
+// the page of their results is returned
+func (p *Player) DetectAndPlay(args shared.DetectArgs) (shared.DetectReply, error) {
+	unknown := args.Query
 	logger.LogInfo("Checking what is this", unknown)
 	whatIsThis := p.CheckWhatIsThis(unknown)
 	switch whatIsThis {
 	case DDir:
 		logger.LogInfo("Detected dir")
-		return nil, p.AddMusicsFromDir(
+		return shared.DetectReply{}, p.AddMusicsFromDir(
 			unknown,
 			func(m db.Music) error {
-				pmusic, err := NewMusic(
-					m.Name,
-					m.Data,
-				)
+				pmusic, err := NewMusicFromDb(m)
 				if err != nil {
 					return err
 				}
@@ -382,13 +565,10 @@ func (p *Player) DetectAndPlay(unknown string) ([]shared.SearchResult, error) {
 		)
 	case DFile:
 		logger.LogInfo("Detected file")
-		return nil, p.AddMusicFromFile(
+		return shared.DetectReply{}, p.AddMusicFromFile(
 			unknown,
 			func(m db.Music) error {
-				pmusic, err := NewMusic(
-					m.Name,
-					m.Data,
-				)
+				pmusic, err := NewMusicFromDb(m)
 				if err != nil {
 					return err
 				}
@@ -409,24 +589,51 @@ func (p *Player) DetectAndPlay(unknown string) ([]shared.SearchResult, error) {
 		} else {
 			m = p.Queue.GetMusicByName(unknown)
 		}
+		p.saveResume()
 		p.Queue.SetCurrrMusic(m)
-		return nil, p.Play()
+		return shared.DetectReply{}, p.Play()
 	case DPlaylist:
-		return nil, p.PlayListPlayAll(
+		return shared.DetectReply{}, p.PlayListPlayAll(
+			unknown,
+		)
+	case DRemotePlaylist:
+		logger.LogInfo("Detected remote playlist", unknown)
+		return shared.DetectReply{}, p.AddMusicsFromPlaylistURL(
+			unknown,
+			func(m db.Music) error {
+				pmusic, err := NewMusicFromDb(m)
+				if err != nil {
+					return err
+				}
+				p.Queue.Enqueue(*pmusic)
+				if p.getPlayerState() == shared.Stopped {
+					return p.Play()
+				}
+				return nil
+			},
+		)
+	case DLive:
+		logger.LogInfo("Detected live stream", unknown)
+		return shared.DetectReply{}, p.AddLive(
 			unknown,
 		)
 	case DUnknown:
 		logger.LogInfo("Detected unknown, searching for", unknown)
-		return p.GetAvailableMusicOptions(unknown), nil
+		return shared.DetectReply{
+			Searched: true,
+			Results: p.Search(shared.SearchArgs{
+				Query:  unknown,
+				Source: args.Source,
+				Offset: args.Offset,
+				Limit:  args.Limit,
+			}),
+		}, nil
 	case DCache:
 		logger.LogInfo("Detected cache, searching for", unknown)
-		return nil, p.AddMusicFromHash(
+		return shared.DetectReply{}, p.AddMusicFromHash(
 			unknown,
 			func(m db.Music) error {
-				pmusic, err := NewMusic(
-					m.Name,
-					m.Data,
-				)
+				pmusic, err := NewMusicFromDb(m)
 				if err != nil {
 					return err
 				}
@@ -439,14 +646,15 @@ func (p *Player) DetectAndPlay(unknown string) ([]shared.SearchResult, error) {
 		)
 	default:
 		logger.LogInfo("Detected Engine", whatIsThis)
-		go p.AddMusicFromOnline(
+		go p.AddMusicFromOnlineStreaming(
 			unknown,
 			string(whatIsThis),
+			func(m *Music) {
+				p.Queue.Enqueue(*m)
+				p.Play()
+			},
 			func(m db.Music) error {
-				pmusic, err := NewMusic(
-					m.Name,
-					m.Data,
-				)
+				pmusic, err := NewMusicFromDb(m)
 				if err != nil {
 					return err
 				}
@@ -456,5 +664,5 @@ func (p *Player) DetectAndPlay(unknown string) ([]shared.SearchResult, error) {
 			},
 		)
 	}
-	return []shared.SearchResult{}, nil
+	return shared.DetectReply{}, nil
 }
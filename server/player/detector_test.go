@@ -0,0 +1,140 @@
+package player
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Malwarize/retro/server/player/db"
+	en "github.com/Malwarize/retro/server/player/engines"
+	"github.com/Malwarize/retro/shared"
+)
+
+// newTestPlayer returns a player on a new database without any engine
+func newTestPlayer(t *testing.T) *Player {
+	t.Helper()
+	d, err := db.LoadDb(filepath.Join(t.TempDir(), "retro.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Player{
+		Queue: NewMusicQueue(),
+		Director: &Director{
+			Db:          d,
+			engines:     make(map[string]en.Engine),
+			unavailable: make(map[string]shared.EngineInfo),
+		},
+		Tasks: make(map[string]shared.Task),
+	}
+}
+
+// addTestMusics caches musics with distinct data under the names
+func addTestMusics(t *testing.T, p *Player, musics ...db.Music) {
+	t.Helper()
+	for _, m := range musics {
+		m.Source = "file"
+		m.Key = "/music/" + m.Name + ".mp3"
+		m.Data = []byte("data of " + m.Name)
+		if err := p.Director.Db.AddMusic(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckWhatIsThisPlaylists(t *testing.T) {
+	p := newTestPlayer(t)
+	if err := p.Director.Db.AddPlaylist("favorites"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Director.Db.AddSmartPlaylist("eighties", "year >= 1980 AND year < 1990"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]DResults{
+		"favorites": DPlaylist,
+		"eighties":  DPlaylist,
+		"nineties":  DUnknown,
+	}
+	for query, want := range tests {
+		if got := p.CheckWhatIsThis(query); got != want {
+			t.Errorf("CheckWhatIsThis(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
+
+func TestDetectAndAddSmartPlaylist(t *testing.T) {
+	p := newTestPlayer(t)
+	addTestMusics(
+		t,
+		p,
+		db.Music{Name: "blue_monday", Year: 1983},
+		db.Music{Name: "one_more_time", Year: 2000},
+		db.Music{Name: "take_on_me", Year: 1985},
+	)
+	if err := p.Director.Db.AddPlaylist("favorites"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Director.Db.AddSmartPlaylist("eighties", "year >= 1980 AND year < 1990"); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := p.DetectAndAddToPlayList(shared.AddToPlayListArgs{
+		Query:        "eighties",
+		PlayListName: "favorites",
+		Position:     db.EndOfPlaylist,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Searched {
+		t.Errorf("the smart playlist was searched: %+v", reply)
+	}
+	ms, err := p.Director.Db.GetMusicsFromPlaylist("favorites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	if want := []string{"blue_monday", "take_on_me"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestDetectAndAddQueueMusic(t *testing.T) {
+	p := newTestPlayer(t)
+	addTestMusics(t, p, db.Music{Name: "blue_monday"})
+	if err := p.Director.Db.AddPlaylist("favorites"); err != nil {
+		t.Fatal(err)
+	}
+	p.Queue.Enqueue(Music{Name: "blue_monday", Data: []byte("data of blue_monday")})
+	p.Queue.Enqueue(Music{Name: "radio_paradise", Live: &liveStream{Url: "http://example.com/stream"}})
+	p.Queue.Enqueue(Music{Name: "gone", Data: []byte("data of gone")})
+
+	add := func(query string) error {
+		_, err := p.DetectAndAddToPlayList(shared.AddToPlayListArgs{
+			Query:        query,
+			PlayListName: "favorites",
+			Position:     db.EndOfPlaylist,
+		})
+		return err
+	}
+	if err := add("blue_monday"); err != nil {
+		t.Fatal(err)
+	}
+	// the live streams and the musics removed from the cache aren't in the library
+	for _, query := range []string{"radio_paradise", "1", "gone"} {
+		if err := add(query); err == nil {
+			t.Errorf("added %s to the playlist", query)
+		}
+	}
+
+	ms, err := p.Director.Db.GetMusicsFromPlaylist("favorites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].Name != "blue_monday" {
+		t.Errorf("unexpected playlist %+v", ms)
+	}
+}
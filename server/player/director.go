@@ -1,9 +1,11 @@
 package player
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/Malwarize/retro/config"
 	"github.com/Malwarize/retro/logger"
@@ -13,9 +15,10 @@ import (
 )
 
 type Director struct {
-	Converter *Converter
-	Db        *db.Db
-	engines   map[string]en.Engine // key: engine name, value: engine
+	Converter   *Converter
+	Db          *db.Db
+	engines     map[string]en.Engine         // key: engine name, value: engine
+	unavailable map[string]shared.EngineInfo // the disabled engines and the ones that failed to start
 }
 
 func NewDirector(db *db.Db) (*Director, error) {
@@ -24,12 +27,56 @@ func NewDirector(db *db.Db) (*Director, error) {
 		return nil, err
 	}
 	return &Director{
-		engines:   make(map[string]en.Engine),
-		Db:        db,
-		Converter: c,
+		engines:     make(map[string]en.Engine),
+		unavailable: make(map[string]shared.EngineInfo),
+		Db:          db,
+		Converter:   c,
 	}, nil
 }
 
+// knownEngines are the engines retro can use, add the new engines here
+var knownEngines = []struct {
+	name string
+	new  func(cfg config.EngineConfig) (en.Engine, error)
+}{
+	{
+		"youtube",
+		func(cfg config.EngineConfig) (en.Engine, error) {
+			return en.NewYoutubeEngine(cfg)
+		},
+	},
+	{
+		"soundcloud",
+		func(cfg config.EngineConfig) (en.Engine, error) {
+			return en.NewSoundcloudEngine(cfg)
+		},
+	},
+	{
+		"radio",
+		func(cfg config.EngineConfig) (en.Engine, error) {
+			return en.NewRadioEngine(cfg)
+		},
+	},
+	{
+		"subsonic",
+		func(cfg config.EngineConfig) (en.Engine, error) {
+			return en.NewSubsonicEngine(cfg)
+		},
+	},
+	{
+		"jellyfin",
+		func(cfg config.EngineConfig) (en.Engine, error) {
+			return en.NewJellyfinEngine(cfg)
+		},
+	},
+	{
+		"podcast",
+		func(cfg config.EngineConfig) (en.Engine, error) {
+			return en.NewPodcastEngine(cfg)
+		},
+	},
+}
+
 func NewDefaultDirector() (*Director, error) {
 	db, err := db.LoadDb(config.GetConfig().DBPath)
 	if err != nil {
@@ -42,58 +89,219 @@ func NewDefaultDirector() (*Director, error) {
 		return nil, err
 	}
 
-	youtubeEngine, err := en.NewYoutubeEngine()
+	for _, known := range knownEngines {
+		director.start(known.name, known.new)
+	}
+	// the other engines of the config are the plugins run by their command
+	var plugins []string
+	for name, cfg := range config.GetConfig().Engines {
+		if !isKnownEngine(name) && cfg.Path != "" {
+			plugins = append(plugins, name)
+		}
+	}
+	sort.Strings(plugins)
+	for _, name := range plugins {
+		director.start(
+			name,
+			func(cfg config.EngineConfig) (en.Engine, error) {
+				return en.NewPluginEngine(name, cfg)
+			},
+		)
+	}
+	if len(director.engines) == 0 {
+		logger.LogWarn("no engine is available, only the local musics can be played")
+	}
+	return director, nil
+}
+
+func isKnownEngine(name string) bool {
+	for _, known := range knownEngines {
+		if known.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// start creates the engine of the name with its config and registers it,
+// the disabled engines and the ones failing to start are kept as unavailable
+func (od *Director) start(name string, new func(cfg config.EngineConfig) (en.Engine, error)) {
+	cfg := config.GetConfig().Engine(name)
+	if !cfg.IsEnabled() {
+		od.unavailable[name] = shared.EngineInfo{
+			Name:     name,
+			Priority: cfg.Priority,
+		}
+		return
+	}
+	engine, err := new(cfg)
 	if err != nil {
-		return director, fmt.Errorf("failed to create youtube engine: %w", err)
+		logger.LogWarn(
+			"failed to create",
+			name,
+			"engine",
+			err,
+		)
+		od.unavailable[name] = shared.EngineInfo{
+			Name:     name,
+			Enabled:  true,
+			Priority: cfg.Priority,
+			Error:    err.Error(),
+		}
+		return
 	}
+	od.Register(engine)
+}
 
-	// register the engines here
-	director.Register(youtubeEngine)
-	return director, nil
+// errOffline is the error of the engines calls in offline mode
+var errOffline = errors.New("retro is offline, only the library can be played")
+
+// Offline tells if the engines must not use the network
+func (od *Director) Offline() bool {
+	cfg := config.GetConfig()
+	return cfg.Offline || cfg.OfflineRun
 }
 
 func (od *Director) Register(engine en.Engine) {
 	od.engines[engine.Name()] = engine
+	delete(od.unavailable, engine.Name())
+}
+
+func (od *Director) priority(name string) int {
+	return config.GetConfig().Engine(name).Priority
+}
+
+// EnginesWith returns the engines having the capability, the highest priority first,
+// there is none in offline mode
+func (od *Director) EnginesWith(has func(en.Capabilities) bool) []en.Engine {
+	if od.Offline() {
+		return nil
+	}
+	var engines []en.Engine
+	for _, engine := range od.engines {
+		if has(engine.Capabilities()) {
+			engines = append(engines, engine)
+		}
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		pi, pj := od.priority(engines[i].Name()), od.priority(engines[j].Name())
+		if pi != pj {
+			return pi > pj
+		}
+		return engines[i].Name() < engines[j].Name()
+	})
+	return engines
+}
+
+// Engines returns the status of the known engines, the highest priority first
+func (od *Director) Engines() []shared.EngineInfo {
+	var infos []shared.EngineInfo
+	for _, engine := range od.engines {
+		info := shared.EngineInfo{
+			Name:         engine.Name(),
+			Enabled:      true,
+			Available:    true,
+			Priority:     od.priority(engine.Name()),
+			MaxResults:   engine.MaxResults(),
+			Capabilities: engine.Capabilities().List(),
+		}
+		if od.Offline() {
+			info.Available = false
+			info.Error = "offline"
+		}
+		infos = append(infos, info)
+	}
+	for _, info := range od.unavailable {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Priority != infos[j].Priority {
+			return infos[i].Priority > infos[j].Priority
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
 }
 
 var times = 0
 
+// Search asks the engine for a page of the query, a zero limit is the max results
+// of the engine, the search stops when ctx is done
 func (od *Director) Search(
+	ctx context.Context,
 	engineName, query string,
+	offset, limit int,
 ) ([]shared.SearchResult, error) {
+	if od.Offline() {
+		return nil, errOffline
+	}
 	engine, ok := od.engines[engineName]
 	if !ok {
 		return nil, errors.New("engine not found")
 	}
+	if !engine.Capabilities().Search {
+		return nil, fmt.Errorf("%s engine can't search", engineName)
+	}
 
-	return engine.Search(query, engine.MaxResults())
+	if limit <= 0 {
+		limit = engine.MaxResults()
+	}
+	return engine.Search(ctx, query, max(offset, 0), limit)
 }
 
-func (od *Director) Download(engineName, url string) (*db.Music, error) {
-	engine, ok := od.engines[engineName]
-	if !ok {
-		return nil, errors.New("engine not found")
-	}
-	// check if file is Cached
-	music, err := od.Db.GetMusic(
-		engine.Name(),
+// Download returns the cached music of the url or downloads it,
+// the download stops when ctx is canceled, the known meta wins
+// over what the engine and the file tags report
+func (od *Director) Download(
+	ctx context.Context,
+	engineName, url string,
+	known shared.MusicMeta,
+	progress func(en.Progress),
+) (*db.Music, error) {
+	// check if file is Cached, the engine may be gone since
+	music, err := od.Db.GetMusicByKeySource(
+		engineName,
 		url,
 	)
 	if err == nil {
 		return &music, nil
 	}
+	if od.Offline() {
+		return nil, errOffline
+	}
+	engine, ok := od.engines[engineName]
+	if !ok {
+		return nil, errors.New("engine not found")
+	}
+	if !engine.Capabilities().Download {
+		return nil, fmt.Errorf("%s engine can't download", engineName)
+	}
 
 	logger.LogInfo("Downloading file from ", url)
-	reader, name, err := engine.Download(url)
+	reader, meta, err := engine.Download(ctx, url, progress)
 	logger.LogInfo("Downloaded file from ", url)
 	if err != nil {
 		return nil, err
 	}
-
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
+	return od.Cache(
+		engine.Name(),
+		url,
+		data,
+		fillMeta(known, meta),
+	)
+}
+
+// Cache converts the downloaded data to mp3 and adds it to the cache,
+// the meta wins over the file tags
+func (od *Director) Cache(
+	engineName, url string,
+	data []byte,
+	meta shared.MusicMeta,
+) (*db.Music, error) {
 	isMp3, err := od.Converter.IsMp3(data)
 	var mp3data []byte
 	if !isMp3 {
@@ -104,13 +312,24 @@ func (od *Director) Download(engineName, url string) (*db.Music, error) {
 	} else {
 		mp3data = data
 	}
+	// fill what the engine didn't report from the file tags
+	probed, err := od.Converter.ProbeMeta(mp3data)
+	if err != nil {
+		logger.LogWarn(
+			"failed to read music metadata",
+			err,
+		)
+	} else {
+		meta = fillMeta(meta, probed)
+	}
 	// cache it to db
-	music = db.Music{
-		Name:   name,
-		Source: engine.Name(),
+	music := db.Music{
+		Name:   meta.Title,
+		Source: engineName,
 		Key:    url,
 		Data:   mp3data,
 	}
+	music.SetMeta(meta)
 	err = od.Db.AddMusic(&music)
 	if err != nil {
 		logger.LogWarn(
@@ -122,6 +341,164 @@ func (od *Director) Download(engineName, url string) (*db.Music, error) {
 	return &music, nil
 }
 
+// streamEngine returns the engine of the name if it can stream its downloads
+func (od *Director) streamEngine(engineName string) (en.StreamEngine, bool) {
+	if od.Offline() {
+		return nil, false
+	}
+	engine, ok := od.engines[engineName]
+	if !ok || !engine.Capabilities().Stream {
+		return nil, false
+	}
+	se, ok := engine.(en.StreamEngine)
+	return se, ok
+}
+
+// CanStream tells if the engine can play its downloads before they end
+func (od *Director) CanStream(engineName string) bool {
+	_, ok := od.streamEngine(engineName)
+	return ok
+}
+
+// DownloadStream returns the audio of the url as mp3 while it is downloaded,
+// the caller caches it with Cache once it is read, the known meta wins
+func (od *Director) DownloadStream(
+	ctx context.Context,
+	engineName, url string,
+	known shared.MusicMeta,
+	progress func(en.Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	engine, ok := od.streamEngine(engineName)
+	if !ok {
+		return nil, shared.MusicMeta{}, fmt.Errorf("%s engine can't stream", engineName)
+	}
+	reader, meta, err := engine.DownloadStream(ctx, url, progress)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	// a constant bitrate mp3 whatever the site gives
+	converted, err := od.Converter.ConvertStreamToMP3(reader)
+	if err != nil {
+		reader.Close()
+		return nil, shared.MusicMeta{}, err
+	}
+	return converted, fillMeta(known, meta), nil
+}
+
+// CachedEngine returns the engine the url was downloaded from when it is
+// in the cache, the url is then played without asking the engines
+func (od *Director) CachedEngine(url string) (string, bool) {
+	for _, info := range od.Engines() {
+		if _, err := od.Db.GetMusicByKeySource(info.Name, url); err == nil {
+			return info.Name, true
+		}
+	}
+	return "", false
+}
+
+// playlistEngine returns the engine that can expand the playlist url
+func (od *Director) playlistEngine(ctx context.Context, url string) (en.PlaylistEngine, bool) {
+	for _, engine := range od.EnginesWith(en.CanExpandPlaylist) {
+		if pe, ok := engine.(en.PlaylistEngine); ok && pe.IsPlaylist(ctx, url) {
+			return pe, true
+		}
+	}
+	return nil, false
+}
+
+// IsPlaylist tells if an engine knows the url as a playlist
+func (od *Director) IsPlaylist(ctx context.Context, url string) bool {
+	_, ok := od.playlistEngine(ctx, url)
+	return ok
+}
+
+// ExpandPlaylist returns the entries of the playlist url
+func (od *Director) ExpandPlaylist(ctx context.Context, url string) ([]shared.SearchResult, error) {
+	engine, ok := od.playlistEngine(ctx, url)
+	if !ok {
+		return nil, errors.New("no engine can expand this playlist")
+	}
+	return engine.ExpandPlaylist(ctx, url)
+}
+
+// RemotePlaylists returns the playlists of the servers of the engines,
+// the engines failing to list them are skipped
+func (od *Director) RemotePlaylists(ctx context.Context) []shared.RemotePlaylist {
+	var playlists []shared.RemotePlaylist
+	for _, engine := range od.EnginesWith(en.CanExpandPlaylist) {
+		lister, ok := engine.(en.PlaylistLister)
+		if !ok {
+			continue
+		}
+		list, err := lister.Playlists(ctx)
+		if err != nil {
+			logger.LogWarn(
+				"failed to list the playlists of",
+				engine.Name(),
+				err,
+			)
+			continue
+		}
+		playlists = append(playlists, list...)
+	}
+	return playlists
+}
+
+// liveEngine returns the engine that can play the url as a live stream
+func (od *Director) liveEngine(ctx context.Context, url string) (en.LiveEngine, bool) {
+	for _, engine := range od.EnginesWith(en.CanStream) {
+		if le, ok := engine.(en.LiveEngine); ok && le.IsLive(ctx, url) {
+			return le, true
+		}
+	}
+	return nil, false
+}
+
+// IsLive tells if an engine knows the url as a live stream
+func (od *Director) IsLive(ctx context.Context, url string) bool {
+	_, ok := od.liveEngine(ctx, url)
+	return ok
+}
+
+// OpenLive connects to the live stream of the url, the audio is converted
+// to mp3 as it arrives when the stream is in another format
+func (od *Director) OpenLive(
+	ctx context.Context,
+	url string,
+	onTitle func(string),
+) (io.ReadCloser, en.LiveInfo, error) {
+	engine, ok := od.liveEngine(ctx, url)
+	if !ok {
+		return nil, en.LiveInfo{}, errors.New("no engine can play this stream")
+	}
+	reader, info, err := engine.OpenLive(ctx, url, onTitle)
+	if err != nil {
+		return nil, info, err
+	}
+	if info.ContentType == "audio/mpeg" || info.ContentType == "audio/mp3" {
+		return reader, info, nil
+	}
+	converted, err := od.Converter.ConvertStreamToMP3(reader)
+	if err != nil {
+		reader.Close()
+		return nil, info, err
+	}
+	return converted, info, nil
+}
+
+// FetchFeed reads the podcast feed of the url
+func (od *Director) FetchFeed(ctx context.Context, url string) (en.Feed, error) {
+	if od.Offline() {
+		return en.Feed{}, errOffline
+	}
+	for _, engine := range od.engines {
+		if fe, ok := engine.(en.FeedEngine); ok {
+			return fe.FetchFeed(ctx, url)
+		}
+	}
+	return en.Feed{}, errors.New("no engine can read podcast feeds")
+}
+
 func (od *Director) GetEngines() map[string]en.Engine {
 	return od.engines
 }
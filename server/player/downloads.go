@@ -0,0 +1,249 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	en "github.com/Malwarize/retro/server/player/engines"
+	"github.com/Malwarize/retro/shared"
+)
+
+type download struct {
+	shared.DownloadTask
+	meta   shared.MusicMeta // what is known of the music before the download
+	how    callback
+	cancel context.CancelFunc
+	// onStream gets the music playing while it downloads, how is then not called
+	onStream func(m *Music)
+}
+
+// downloadManager runs the downloads with a limit on how many run at the same time,
+// the failed and canceled downloads are kept so they can be retried
+type downloadManager struct {
+	mu        sync.Mutex
+	slots     chan struct{}
+	downloads map[int]*download
+	nextId    int
+}
+
+func newDownloadManager(parallel int) *downloadManager {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	return &downloadManager{
+		slots:     make(chan struct{}, parallel),
+		downloads: make(map[int]*download),
+		nextId:    1,
+	}
+}
+
+func (dm *downloadManager) add(target, engine string, meta shared.MusicMeta, how callback) *download {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	d := &download{
+		DownloadTask: shared.DownloadTask{
+			Id:     dm.nextId,
+			Target: target,
+			Engine: engine,
+		},
+		meta: meta,
+		how:  how,
+	}
+	dm.downloads[d.Id] = d
+	dm.nextId++
+	return d
+}
+
+func (dm *downloadManager) get(id int) (*download, bool) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	d, ok := dm.downloads[id]
+	return d, ok
+}
+
+func (dm *downloadManager) remove(id int) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	delete(dm.downloads, id)
+}
+
+// update changes the download under the lock
+func (dm *downloadManager) update(d *download, change func(d *download)) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	change(d)
+}
+
+func (dm *downloadManager) list() []shared.DownloadTask {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	var tasks []shared.DownloadTask
+	for _, d := range dm.downloads {
+		tasks = append(tasks, d.DownloadTask)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Id < tasks[j].Id
+	})
+	return tasks
+}
+
+// runDownload waits for a free slot then downloads the music and passes it to the callback
+func (p *Player) runDownload(d *download) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.downloads.update(d, func(d *download) {
+		d.State = shared.DownloadQueued
+		d.Percent = 0
+		d.Speed = ""
+		d.ETA = ""
+		d.Error = ""
+		d.cancel = cancel
+	})
+	p.addTask(d.Target, shared.Downloading)
+
+	select {
+	case p.downloads.slots <- struct{}{}:
+		defer func() { <-p.downloads.slots }()
+	case <-ctx.Done():
+		return p.failDownload(d, shared.DownloadCanceled, ctx.Err())
+	}
+	p.downloads.update(d, func(d *download) {
+		d.State = shared.DownloadRunning
+	})
+
+	progress := func(progress en.Progress) {
+		p.downloads.update(d, func(d *download) {
+			d.Percent = progress.Percent
+			d.Speed = progress.Speed
+			d.ETA = progress.ETA
+		})
+		p.setTaskProgress(d.Target, progress.Percent)
+	}
+	var music *db.Music
+	var err error
+	streamed := false
+	if p.streams(d) {
+		music, streamed, err = p.streamDownload(ctx, d, progress)
+		if streamed && err != nil {
+			// a retry downloads it again and plays it once cached
+			p.downloads.update(d, func(d *download) {
+				d.onStream = nil
+			})
+		}
+	} else {
+		music, err = p.Director.Download(
+			ctx,
+			d.Engine,
+			d.Target,
+			d.meta,
+			progress,
+		)
+	}
+	if ctx.Err() != nil {
+		return p.failDownload(d, shared.DownloadCanceled, ctx.Err())
+	}
+	if err == nil && len(music.Data) == 0 {
+		err = errors.New("empty music")
+	}
+	if err != nil {
+		return p.failDownload(d, shared.DownloadFailed, err)
+	}
+
+	if d.how != nil && !streamed {
+		if err := d.how(*music); err != nil {
+			return p.failDownload(d, shared.DownloadFailed, err)
+		}
+	}
+	p.downloads.remove(d.Id)
+	p.removeTask(d.Target)
+	// the new music may push the cache over its limit
+	go p.EvictCache()
+	return nil
+}
+
+// streams tells if the download can play while it downloads,
+// the cached musics are played from the cache
+func (p *Player) streams(d *download) bool {
+	if d.onStream == nil || !p.Director.CanStream(d.Engine) {
+		return false
+	}
+	_, err := p.Director.Db.GetMusicByKeySource(d.Engine, d.Target)
+	return err != nil
+}
+
+func (p *Player) failDownload(d *download, state shared.DownloadState, err error) error {
+	p.downloads.update(d, func(d *download) {
+		d.State = state
+		d.Error = err.Error()
+		d.cancel = nil
+	})
+	p.errorTask(d.Target, err)
+	return logger.LogError(
+		logger.GError(
+			"Failed to download music",
+			err,
+		),
+	)
+}
+
+func (p *Player) Downloads() []shared.DownloadTask {
+	return p.downloads.list()
+}
+
+// CancelDownload stops a queued or running download, its process is killed
+func (p *Player) CancelDownload(id int) error {
+	d, ok := p.downloads.get(id)
+	if !ok {
+		return logger.LogError(
+			logger.GError(
+				"Download not found",
+			),
+		)
+	}
+	var cancel context.CancelFunc
+	p.downloads.update(d, func(d *download) {
+		cancel = d.cancel
+	})
+	if cancel == nil {
+		return logger.LogError(
+			logger.GError(
+				"Download is not running",
+			),
+		)
+	}
+	cancel()
+	return nil
+}
+
+// RetryDownload restarts a failed or canceled download in the background
+func (p *Player) RetryDownload(id int) error {
+	d, ok := p.downloads.get(id)
+	if !ok {
+		return logger.LogError(
+			logger.GError(
+				"Download not found",
+			),
+		)
+	}
+	var state shared.DownloadState
+	p.downloads.update(d, func(d *download) {
+		state = d.State
+		if state == shared.DownloadFailed || state == shared.DownloadCanceled {
+			// mark it queued now so a second retry is refused
+			d.State = shared.DownloadQueued
+		}
+	})
+	if state != shared.DownloadFailed && state != shared.DownloadCanceled {
+		return logger.LogError(
+			logger.GError(
+				"Only failed or canceled downloads can be retried",
+			),
+		)
+	}
+	go p.runDownload(d)
+	return nil
+}
@@ -0,0 +1,133 @@
+package engines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Malwarize/retro/shared"
+)
+
+// httpGet gets the url, the responses other than 200 are errors
+func httpGet(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return httpDo(client, req)
+}
+
+// httpDo sends the request, the responses other than 200 are errors
+func httpDo(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// progressReader reports the progress of a download of total bytes
+type progressReader struct {
+	r        io.Reader
+	total    int64
+	read     int64
+	start    time.Time
+	last     time.Time
+	progress func(Progress)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.read += int64(n)
+	// report twice a second at most
+	if now := time.Now(); now.Sub(pr.last) >= 500*time.Millisecond || err == io.EOF {
+		pr.last = now
+		pr.report(now)
+	}
+	return n, err
+}
+
+func (pr *progressReader) report(now time.Time) {
+	var p Progress
+	if pr.total > 0 {
+		p.Percent = float64(pr.read) * 100 / float64(pr.total)
+	}
+	elapsed := now.Sub(pr.start).Seconds()
+	if elapsed > 0 {
+		speed := float64(pr.read) / elapsed
+		p.Speed = shared.FormatSize(int64(speed)) + "/s"
+		if pr.total > 0 && speed > 0 {
+			eta := time.Duration(float64(pr.total-pr.read) / speed * float64(time.Second))
+			p.ETA = shared.DurationToString(eta.Round(time.Second))
+		}
+	}
+	pr.progress(p)
+}
+
+// errStalled is the error of the reads of a stream sending nothing for too long
+var errStalled = errors.New("the stream sent nothing for too long")
+
+// idleReader cancels the request of its body when a read waits longer than timeout,
+// the time between the reads doesn't count
+type idleReader struct {
+	body    io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+	stalled atomic.Bool
+}
+
+func newIdleReader(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleReader {
+	ir := &idleReader{
+		body:    body,
+		timeout: timeout,
+		cancel:  cancel,
+	}
+	ir.timer = time.AfterFunc(timeout, func() {
+		ir.stalled.Store(true)
+		cancel()
+	})
+	ir.timer.Stop()
+	return ir
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	ir.timer.Reset(ir.timeout)
+	n, err := ir.body.Read(p)
+	ir.timer.Stop()
+	if err != nil && ir.stalled.Load() {
+		return n, errStalled
+	}
+	return n, err
+}
+
+// Close closes the body and cancels its request
+func (ir *idleReader) Close() error {
+	ir.timer.Stop()
+	ir.cancel()
+	return ir.body.Close()
+}
+
+// readBody reads the whole body of the response, progress is called as it arrives
+func readBody(resp *http.Response, progress func(Progress)) ([]byte, error) {
+	var body io.Reader = resp.Body
+	if progress != nil {
+		now := time.Now()
+		body = &progressReader{
+			r:        resp.Body,
+			total:    resp.ContentLength,
+			start:    now,
+			last:     now,
+			progress: progress,
+		}
+	}
+	return io.ReadAll(body)
+}
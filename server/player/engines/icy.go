@@ -0,0 +1,72 @@
+package engines
+
+import (
+	"io"
+	"strings"
+)
+
+// icyReader strips the ICY metadata blocks the radios insert every metaint bytes
+// of audio, and reports the StreamTitle they carry
+type icyReader struct {
+	r       io.Reader
+	metaint int
+	left    int // audio bytes before the next metadata block
+	onTitle func(title string)
+}
+
+func newIcyReader(r io.Reader, metaint int, onTitle func(string)) *icyReader {
+	return &icyReader{
+		r:       r,
+		metaint: metaint,
+		left:    metaint,
+		onTitle: onTitle,
+	}
+}
+
+func (ir *icyReader) Read(p []byte) (int, error) {
+	if ir.left == 0 {
+		if err := ir.readMeta(); err != nil {
+			return 0, err
+		}
+		ir.left = ir.metaint
+	}
+	if len(p) > ir.left {
+		p = p[:ir.left]
+	}
+	n, err := ir.r.Read(p)
+	ir.left -= n
+	return n, err
+}
+
+// readMeta reads a metadata block, its first byte is its length divided by 16
+func (ir *icyReader) readMeta() error {
+	var length [1]byte
+	if _, err := io.ReadFull(ir.r, length[:]); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+	meta := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(ir.r, meta); err != nil {
+		return err
+	}
+	if title, ok := parseStreamTitle(string(meta)); ok && ir.onTitle != nil {
+		ir.onTitle(title)
+	}
+	return nil
+}
+
+// parseStreamTitle finds the title in a metadata block like: StreamTitle='Artist - Title';
+func parseStreamTitle(meta string) (string, bool) {
+	meta = strings.TrimRight(meta, "\x00")
+	_, rest, found := strings.Cut(meta, "StreamTitle='")
+	if !found {
+		return "", false
+	}
+	title, _, found := strings.Cut(rest, "';")
+	if !found {
+		title = strings.TrimSuffix(rest, "'")
+	}
+	return strings.TrimSpace(title), true
+}
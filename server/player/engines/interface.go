@@ -1,15 +1,116 @@
 package engines
 
 import (
+	"context"
 	"io"
 
 	"github.com/Malwarize/retro/shared"
 )
 
+// Progress is the state of a download as reported by the engine
+type Progress struct {
+	Percent float64
+	Speed   string
+	ETA     string
+}
+
+// Capabilities tells what an engine can do, the director only asks
+// the engines having the capability it needs
+type Capabilities struct {
+	Search   bool // find musics from a query
+	Download bool // download the music of an url
+	Stream   bool // play an url while it downloads
+	Playlist bool // expand a playlist url into its entries
+	Metadata bool // report the title, artist... of the downloaded musics
+}
+
+// List returns the names of the capabilities the engine has
+func (c Capabilities) List() []string {
+	var names []string
+	for _, capability := range []struct {
+		name string
+		has  bool
+	}{
+		{"search", c.Search},
+		{"download", c.Download},
+		{"stream", c.Stream},
+		{"playlist", c.Playlist},
+		{"metadata", c.Metadata},
+	} {
+		if capability.has {
+			names = append(names, capability.name)
+		}
+	}
+	return names
+}
+
+func CanSearch(c Capabilities) bool {
+	return c.Search
+}
+
+func CanDownload(c Capabilities) bool {
+	return c.Download
+}
+
+func CanStream(c Capabilities) bool {
+	return c.Stream
+}
+
+func CanExpandPlaylist(c Capabilities) bool {
+	return c.Playlist
+}
+
+// Engine is a source of musics, every call stops when its ctx is canceled
+// or times out, the engines must not leave any process or goroutine behind
 type Engine interface {
-	Search(query string, maxResults int) ([]shared.SearchResult, error)
-	Download(url string) (io.ReadCloser, string, error)
-	Exists(url string) (bool, error)
+	// Search returns at most limit results, skipping the offset first ones
+	Search(ctx context.Context, query string, offset, limit int) ([]shared.SearchResult, error)
+	// Download calls progress as the download goes
+	Download(ctx context.Context, url string, progress func(Progress)) (io.ReadCloser, shared.MusicMeta, error)
+	Exists(ctx context.Context, url string) (bool, error)
 	Name() string
 	MaxResults() int
+	Capabilities() Capabilities
+}
+
+// PlaylistEngine is implemented by the engines that can expand
+// a playlist url into its entries
+type PlaylistEngine interface {
+	// IsPlaylist may ask the server when the url doesn't tell
+	IsPlaylist(ctx context.Context, url string) bool
+	ExpandPlaylist(ctx context.Context, url string) ([]shared.SearchResult, error)
+}
+
+// PlaylistLister is implemented by the playlist engines that can list
+// the playlists of their server, their urls are expanded by ExpandPlaylist
+type PlaylistLister interface {
+	Playlists(ctx context.Context) ([]shared.RemotePlaylist, error)
+}
+
+// LiveInfo describes a live stream
+type LiveInfo struct {
+	Name        string // name of the station
+	ContentType string // format of the audio
+}
+
+// LiveEngine is implemented by the engines playing endless streams like the radios,
+// their audio is played as it arrives and never cached
+type LiveEngine interface {
+	IsLive(ctx context.Context, url string) bool
+	// OpenLive calls onTitle when the title of the stream changes
+	OpenLive(ctx context.Context, url string, onTitle func(string)) (io.ReadCloser, LiveInfo, error)
+}
+
+// StreamEngine is implemented by the engines that can give the audio of an url
+// while they download it, the player starts it before the download ends
+type StreamEngine interface {
+	// DownloadStream returns the audio as it is downloaded, the reader
+	// ends with the download and fails with it
+	DownloadStream(ctx context.Context, url string, progress func(Progress)) (io.ReadCloser, shared.MusicMeta, error)
+}
+
+// FeedEngine is implemented by the engines reading podcast feeds,
+// the episodes are downloaded by the engine like any url
+type FeedEngine interface {
+	FetchFeed(ctx context.Context, url string) (Feed, error)
 }
@@ -0,0 +1,415 @@
+package engines
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// jellyfinEngine plays the music library of a Jellyfin server, its songs
+// are known by their link in the web client
+// e.g. https://jellyfin.example.com/web/#/details?id=abc
+type jellyfinEngine struct {
+	client     *http.Client
+	server     *url.URL
+	apiKey     string
+	username   string
+	maxResults int
+
+	mu     sync.Mutex
+	userId string            // id of the username, found on the first request
+	types  map[string]string // type of the items by id, the links are looked up once
+}
+
+func NewJellyfinEngine(cfg config.EngineConfig) (*jellyfinEngine, error) {
+	if cfg.Url == "" || cfg.ApiKey == "" {
+		return nil, errors.New("no server url or api key in the config")
+	}
+	server, err := url.Parse(strings.TrimSuffix(cfg.Url, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if server.Scheme != "http" && server.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server url: %s", cfg.Url)
+	}
+	maxResults := cfg.MaxResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+	return &jellyfinEngine{
+		client:     &http.Client{},
+		server:     server,
+		apiKey:     cfg.ApiKey,
+		username:   cfg.Username,
+		maxResults: maxResults,
+		types:      make(map[string]string),
+	}, nil
+}
+
+func (je *jellyfinEngine) Name() string {
+	return "jellyfin"
+}
+
+func (je *jellyfinEngine) MaxResults() int {
+	return je.maxResults
+}
+
+func (je *jellyfinEngine) Capabilities() Capabilities {
+	return Capabilities{
+		Search:   true,
+		Download: true,
+		Playlist: true,
+		Metadata: true,
+	}
+}
+
+func (je *jellyfinEngine) endpoint(path string, params url.Values) string {
+	u := *je.server
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+// get calls the API with the api key in the authorization header
+func (je *jellyfinEngine) get(ctx context.Context, path string, params url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, je.endpoint(path, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(`MediaBrowser Client="retro", Device="retro", DeviceId="retro", Version="%s", Token="%s"`, shared.Version, je.apiKey),
+	)
+	return httpDo(je.client, req)
+}
+
+func (je *jellyfinEngine) getJSON(ctx context.Context, path string, params url.Values, out any) error {
+	resp, err := je.get(ctx, path, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// user returns the id of the username of the config, the api keys of
+// the servers older than 10.9 need it to list the items
+func (je *jellyfinEngine) user(ctx context.Context) (string, error) {
+	if je.username == "" {
+		return "", nil
+	}
+	je.mu.Lock()
+	defer je.mu.Unlock()
+	if je.userId != "" {
+		return je.userId, nil
+	}
+	var users []struct {
+		Id   string `json:"Id"`
+		Name string `json:"Name"`
+	}
+	if err := je.getJSON(ctx, "/Users", nil, &users); err != nil {
+		return "", err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Name, je.username) {
+			je.userId = user.Id
+			return je.userId, nil
+		}
+	}
+	return "", fmt.Errorf("jellyfin user %s not found", je.username)
+}
+
+type jellyfinItem struct {
+	Id             string   `json:"Id"`
+	Name           string   `json:"Name"`
+	Type           string   `json:"Type"`
+	Album          string   `json:"Album"`
+	AlbumArtist    string   `json:"AlbumArtist"`
+	Artists        []string `json:"Artists"`
+	RunTimeTicks   int64    `json:"RunTimeTicks"`
+	IndexNumber    int      `json:"IndexNumber"`
+	ProductionYear int      `json:"ProductionYear"`
+	ChildCount     int      `json:"ChildCount"`
+}
+
+func (i jellyfinItem) artist() string {
+	if len(i.Artists) > 0 {
+		return strings.Join(i.Artists, ", ")
+	}
+	return i.AlbumArtist
+}
+
+// duration converts the ticks of 100ns of jellyfin
+func (i jellyfinItem) duration() time.Duration {
+	return time.Duration(i.RunTimeTicks * 100)
+}
+
+func (i jellyfinItem) meta() shared.MusicMeta {
+	return shared.MusicMeta{
+		Title:       i.Name,
+		Artist:      i.artist(),
+		Album:       i.Album,
+		Duration:    i.duration(),
+		TrackNumber: i.IndexNumber,
+		Year:        i.ProductionYear,
+	}
+}
+
+// items lists the items matching the params
+func (je *jellyfinEngine) items(ctx context.Context, path string, params url.Values) ([]jellyfinItem, error) {
+	userId, err := je.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userId != "" {
+		params.Set("userId", userId)
+	}
+	var res struct {
+		Items []jellyfinItem `json:"Items"`
+	}
+	if err := je.getJSON(ctx, path, params, &res); err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (je *jellyfinEngine) item(ctx context.Context, id string) (jellyfinItem, error) {
+	items, err := je.items(ctx, "/Items", url.Values{"ids": {id}})
+	if err != nil {
+		return jellyfinItem{}, err
+	}
+	if len(items) == 0 {
+		return jellyfinItem{}, errors.New("jellyfin item not found")
+	}
+	je.mu.Lock()
+	je.types[id] = items[0].Type
+	je.mu.Unlock()
+	return items[0], nil
+}
+
+// itemType returns the type of the item, the items don't change type
+// so a song link is looked up once by IsPlaylist then Exists
+func (je *jellyfinEngine) itemType(ctx context.Context, id string) (string, error) {
+	je.mu.Lock()
+	itemType, ok := je.types[id]
+	je.mu.Unlock()
+	if ok {
+		return itemType, nil
+	}
+	item, err := je.item(ctx, id)
+	return item.Type, err
+}
+
+// link is the link of the item in the web client, it is the key of the song in the cache
+func (je *jellyfinEngine) link(id string) string {
+	u := *je.server
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/web/"
+	u.Fragment = "/details?id=" + id
+	return u.String()
+}
+
+// idOf returns the id of the item of a web client link of the server,
+// the old clients have links like /web/index.html#!/details?id=abc
+func (je *jellyfinEngine) idOf(rawUrl string) (string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host != je.server.Host {
+		return "", false
+	}
+	route, query, _ := strings.Cut(strings.TrimLeft(u.Fragment, "!/"), "?")
+	if route != "details" && route != "details.html" && route != "item" {
+		return "", false
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", false
+	}
+	id := values.Get("id")
+	return id, id != ""
+}
+
+// playlistIdOf returns the id of a playlist url from Playlists
+// e.g. https://jellyfin.example.com/Playlists/abc/Items
+func (je *jellyfinEngine) playlistIdOf(rawUrl string) (string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host != je.server.Host {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(u.Path, strings.TrimSuffix(je.server.Path, "/")+"/Playlists/")
+	if !ok {
+		return "", false
+	}
+	id, ok := strings.CutSuffix(rest, "/Items")
+	return id, ok && id != "" && !strings.Contains(id, "/")
+}
+
+func (je *jellyfinEngine) result(item jellyfinItem) shared.SearchResult {
+	return shared.SearchResult{
+		Title:       item.Name,
+		Destination: je.link(item.Id),
+		Type:        je.Name(),
+		Duration:    item.duration(),
+		Artist:      item.artist(),
+		Album:       item.Album,
+	}
+}
+
+func (je *jellyfinEngine) Search(ctx context.Context, query string, offset, limit int) ([]shared.SearchResult, error) {
+	items, err := je.items(
+		ctx,
+		"/Items",
+		url.Values{
+			"searchTerm":       {query},
+			"includeItemTypes": {"Audio"},
+			"recursive":        {"true"},
+			"startIndex":       {strconv.Itoa(offset)},
+			"limit":            {strconv.Itoa(limit)},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var results []shared.SearchResult
+	for _, item := range items {
+		results = append(results, je.result(item))
+	}
+	return results, nil
+}
+
+// Exists tells if the link is a song of the server
+func (je *jellyfinEngine) Exists(ctx context.Context, link string) (bool, error) {
+	id, ok := je.idOf(link)
+	if !ok {
+		return false, nil
+	}
+	itemType, err := je.itemType(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return itemType == "Audio", nil
+}
+
+func (je *jellyfinEngine) Download(
+	ctx context.Context,
+	link string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	id, ok := je.idOf(link)
+	if !ok {
+		return nil, shared.MusicMeta{}, errors.New("not a song of the jellyfin server")
+	}
+	item, err := je.item(ctx, id)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	if item.Type != "Audio" {
+		return nil, shared.MusicMeta{}, fmt.Errorf("jellyfin item %s is not a song", item.Name)
+	}
+	logger.LogInfo("Downloading", item.Name, "from", link)
+
+	// the original file, the director converts it when it isn't mp3
+	resp, err := je.get(ctx, "/Audio/"+url.PathEscape(id)+"/stream", url.Values{"static": {"true"}})
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	defer resp.Body.Close()
+	data, err := readBody(resp, progress)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), item.meta(), nil
+}
+
+// IsPlaylist tells if the url is a playlist of the server, the web client links
+// are looked up since the songs and the playlists share them
+func (je *jellyfinEngine) IsPlaylist(ctx context.Context, playlistUrl string) bool {
+	if _, ok := je.playlistIdOf(playlistUrl); ok {
+		return true
+	}
+	id, ok := je.idOf(playlistUrl)
+	if !ok {
+		return false
+	}
+	itemType, err := je.itemType(ctx, id)
+	return err == nil && (itemType == "Playlist" || itemType == "MusicAlbum")
+}
+
+// ExpandPlaylist returns the songs of a playlist or an album of the server
+func (je *jellyfinEngine) ExpandPlaylist(ctx context.Context, playlistUrl string) ([]shared.SearchResult, error) {
+	id, ok := je.playlistIdOf(playlistUrl)
+	if !ok {
+		id, ok = je.idOf(playlistUrl)
+	}
+	if !ok {
+		return nil, errors.New("not a playlist of the jellyfin server")
+	}
+	item, err := je.item(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var items []jellyfinItem
+	if item.Type == "Playlist" {
+		items, err = je.items(ctx, "/Playlists/"+url.PathEscape(id)+"/Items", url.Values{})
+	} else {
+		items, err = je.items(
+			ctx,
+			"/Items",
+			url.Values{
+				"parentId":         {id},
+				"includeItemTypes": {"Audio"},
+				"recursive":        {"true"},
+				"sortBy":           {"ParentIndexNumber,IndexNumber,SortName"},
+			},
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results []shared.SearchResult
+	for _, item := range items {
+		if item.Type != "Audio" {
+			continue
+		}
+		results = append(results, je.result(item))
+	}
+	return results, nil
+}
+
+// Playlists returns the music playlists of the server
+func (je *jellyfinEngine) Playlists(ctx context.Context) ([]shared.RemotePlaylist, error) {
+	items, err := je.items(
+		ctx,
+		"/Items",
+		url.Values{
+			"includeItemTypes": {"Playlist"},
+			"mediaTypes":       {"Audio"},
+			"recursive":        {"true"},
+			"fields":           {"ChildCount"},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var playlists []shared.RemotePlaylist
+	for _, item := range items {
+		playlists = append(playlists, shared.RemotePlaylist{
+			Name:   item.Name,
+			Url:    je.endpoint("/Playlists/"+url.PathEscape(item.Id)+"/Items", nil),
+			Engine: je.Name(),
+			Songs:  item.ChildCount,
+		})
+	}
+	return playlists, nil
+}
@@ -0,0 +1,309 @@
+package engines
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/shared"
+)
+
+const (
+	jellyfinApiKey = "0123456789abcdef"
+	jellyfinAudio  = "ID3 not really an mp3"
+)
+
+var jellyfinItems = map[string]jellyfinItem{
+	"song-1": {
+		Id:             "song-1",
+		Name:           "Blue Monday",
+		Type:           "Audio",
+		Album:          "Power, Corruption & Lies",
+		AlbumArtist:    "New Order",
+		RunTimeTicks:   int64(448 * time.Second / 100),
+		IndexNumber:    5,
+		ProductionYear: 1983,
+	},
+	"song-2": {
+		Id:           "song-2",
+		Name:         "Ceremony",
+		Type:         "Audio",
+		Artists:      []string{"New Order", "Joy Division"},
+		RunTimeTicks: int64(264 * time.Second / 100),
+	},
+	"album-1": {
+		Id:   "album-1",
+		Name: "Power, Corruption & Lies",
+		Type: "MusicAlbum",
+	},
+	"playlist-1": {
+		Id:         "playlist-1",
+		Name:       "Eighties",
+		Type:       "Playlist",
+		ChildCount: 2,
+	},
+	"video-1": {
+		Id:   "video-1",
+		Name: "Concert",
+		Type: "Video",
+	},
+}
+
+// jellyfinServer answers the endpoints retro calls and checks the api key of each request,
+// it returns the paths of the requests with their item ids
+func jellyfinServer(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), `Token="`+jellyfinApiKey+`"`) {
+			t.Errorf("no api key in %s", r.URL)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if strings.Contains(r.URL.RawQuery, jellyfinApiKey) {
+			t.Errorf("the api key is sent in %s", r.URL)
+		}
+		query := r.URL.Query()
+		mu.Lock()
+		calls = append(calls, strings.TrimSpace(r.URL.Path+" "+query.Get("ids")))
+		mu.Unlock()
+
+		var items []jellyfinItem
+		switch {
+		case r.URL.Path == "/Users":
+			json.NewEncoder(w).Encode([]map[string]string{
+				{"Id": "user-0", "Name": "someone"},
+				{"Id": "user-1", "Name": "Retro"},
+			})
+			return
+		case r.URL.Path == "/Audio/song-1/stream":
+			if query.Get("static") != "true" {
+				t.Errorf("the stream isn't the original file: %s", r.URL)
+			}
+			w.Write([]byte(jellyfinAudio))
+			return
+		case r.URL.Path == "/Playlists/playlist-1/Items":
+			items = []jellyfinItem{jellyfinItems["song-2"], jellyfinItems["video-1"], jellyfinItems["song-1"]}
+		case r.URL.Path != "/Items":
+			http.NotFound(w, r)
+			return
+		case query.Get("ids") != "":
+			if item, ok := jellyfinItems[query.Get("ids")]; ok {
+				items = []jellyfinItem{item}
+			}
+		case query.Get("searchTerm") != "":
+			if query.Get("includeItemTypes") != "Audio" || query.Get("startIndex") != "0" || query.Get("limit") != "10" {
+				t.Errorf("unexpected search %s", r.URL)
+			}
+			items = []jellyfinItem{jellyfinItems["song-1"], jellyfinItems["song-2"]}
+		case query.Get("parentId") == "album-1":
+			items = []jellyfinItem{jellyfinItems["song-1"]}
+		case query.Get("includeItemTypes") == "Playlist":
+			items = []jellyfinItem{jellyfinItems["playlist-1"]}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Items": items})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, calls...)
+	}
+}
+
+func newTestJellyfin(t *testing.T, server *httptest.Server, username string) *jellyfinEngine {
+	engine, err := NewJellyfinEngine(config.EngineConfig{
+		Url:      server.URL + "/",
+		ApiKey:   jellyfinApiKey,
+		Username: username,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestJellyfinSearch(t *testing.T) {
+	server, calls := jellyfinServer(t)
+	engine := newTestJellyfin(t, server, "")
+
+	results, err := engine.Search(context.Background(), "new order", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []shared.SearchResult{
+		{
+			Title:       "Blue Monday",
+			Destination: server.URL + "/web/#/details?id=song-1",
+			Type:        "jellyfin",
+			Duration:    448 * time.Second,
+			Artist:      "New Order",
+			Album:       "Power, Corruption & Lies",
+		},
+		{
+			Title:       "Ceremony",
+			Destination: server.URL + "/web/#/details?id=song-2",
+			Type:        "jellyfin",
+			Duration:    264 * time.Second,
+			Artist:      "New Order, Joy Division",
+		},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got %+v, want %+v", results, want)
+	}
+	if got := calls(); !reflect.DeepEqual(got, []string{"/Items"}) {
+		t.Errorf("unexpected calls %v", got)
+	}
+}
+
+func TestJellyfinUser(t *testing.T) {
+	server, calls := jellyfinServer(t)
+	engine := newTestJellyfin(t, server, "retro")
+
+	for i := 0; i < 2; i++ {
+		if _, err := engine.Search(context.Background(), "new order", 0, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the user is looked up once
+	if got, want := calls(), []string{"/Users", "/Items", "/Items"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+	if engine.userId != "user-1" {
+		t.Errorf("got user %q, want user-1", engine.userId)
+	}
+
+	engine = newTestJellyfin(t, server, "nobody")
+	if _, err := engine.Search(context.Background(), "new order", 0, 10); err == nil {
+		t.Error("searched as a missing user")
+	}
+}
+
+func TestJellyfinLinks(t *testing.T) {
+	server, calls := jellyfinServer(t)
+	engine := newTestJellyfin(t, server, "")
+	ctx := context.Background()
+
+	song := server.URL + "/web/#/details?id=song-1"
+	// a song link is looked up once by the detection of the player
+	if engine.IsPlaylist(ctx, song) {
+		t.Errorf("%s is a playlist", song)
+	}
+	if ok, err := engine.Exists(ctx, song); !ok || err != nil {
+		t.Errorf("%s doesn't exist: %v", song, err)
+	}
+	if got := calls(); !reflect.DeepEqual(got, []string{"/Items song-1"}) {
+		t.Errorf("unexpected calls %v", got)
+	}
+
+	tests := []struct {
+		link     string
+		playlist bool
+		exists   bool
+	}{
+		{server.URL + "/web/index.html#!/details?id=song-2", false, true},
+		{server.URL + "/web/#/details?id=album-1", true, false},
+		{server.URL + "/web/#/details?id=playlist-1", true, false},
+		{server.URL + "/Playlists/playlist-1/Items", true, false},
+		{server.URL + "/web/#/details?id=video-1", false, false},
+		{server.URL + "/web/#/home", false, false},
+		{"https://elsewhere.example.com/web/#/details?id=song-1", false, false},
+	}
+	for _, test := range tests {
+		if got := engine.IsPlaylist(ctx, test.link); got != test.playlist {
+			t.Errorf("IsPlaylist(%s) = %v, want %v", test.link, got, test.playlist)
+		}
+		if got, _ := engine.Exists(ctx, test.link); got != test.exists {
+			t.Errorf("Exists(%s) = %v, want %v", test.link, got, test.exists)
+		}
+	}
+
+	// the lookup stops with the context of the caller
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	engine = newTestJellyfin(t, server, "")
+	if engine.IsPlaylist(canceled, server.URL+"/web/#/details?id=album-1") {
+		t.Error("a canceled lookup found a playlist")
+	}
+}
+
+func TestJellyfinDownload(t *testing.T) {
+	server, _ := jellyfinServer(t)
+	engine := newTestJellyfin(t, server, "")
+
+	body, meta, err := engine.Download(context.Background(), server.URL+"/web/#/details?id=song-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != jellyfinAudio {
+		t.Errorf("got %q, want %q", data, jellyfinAudio)
+	}
+	want := shared.MusicMeta{
+		Title:       "Blue Monday",
+		Artist:      "New Order",
+		Album:       "Power, Corruption & Lies",
+		Duration:    448 * time.Second,
+		TrackNumber: 5,
+		Year:        1983,
+	}
+	if meta != want {
+		t.Errorf("got %+v, want %+v", meta, want)
+	}
+
+	_, _, err = engine.Download(context.Background(), server.URL+"/web/#/details?id=album-1", nil)
+	if err == nil {
+		t.Error("downloaded an album")
+	}
+}
+
+func TestJellyfinPlaylists(t *testing.T) {
+	server, _ := jellyfinServer(t)
+	engine := newTestJellyfin(t, server, "")
+	ctx := context.Background()
+
+	playlists, err := engine.Playlists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlaylists := []shared.RemotePlaylist{{
+		Name:   "Eighties",
+		Url:    server.URL + "/Playlists/playlist-1/Items",
+		Engine: "jellyfin",
+		Songs:  2,
+	}}
+	if !reflect.DeepEqual(playlists, wantPlaylists) {
+		t.Fatalf("got %+v, want %+v", playlists, wantPlaylists)
+	}
+
+	tests := map[string][]string{
+		// the video of the playlist is skipped
+		playlists[0].Url: {"Ceremony", "Blue Monday"},
+		server.URL + "/web/#/details?id=album-1": {"Blue Monday"},
+	}
+	for link, want := range tests {
+		songs, err := engine.ExpandPlaylist(ctx, link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, song := range songs {
+			titles = append(titles, song.Title)
+		}
+		if !reflect.DeepEqual(titles, want) {
+			t.Errorf("ExpandPlaylist(%s) = %v, want %v", link, titles, want)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package engines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// pluginStartTimeout bounds the capabilities request sent when the plugin is created
+const pluginStartTimeout = 10 * time.Second
+
+// pluginEngine is an engine run by an external command, each request starts
+// the command with one JSON request on its stdin, the command answers with
+// JSON lines on its stdout, the lines with a progress are the progress of a
+// download and the last line is the response:
+//
+//	{"action": "capabilities"}                      -> {"capabilities": ["search", "download", "metadata"]}
+//	{"action": "search", "query": "q", "max_results": 10} -> {"results": [{"title": "t", "url": "u", "duration": 215, "artist": "a", "album": "b"}]}
+//	{"action": "exists", "url": "u"}                -> {"exists": true}
+//	{"action": "download", "url": "u", "dir": "/tmp/d"} -> {"progress": 42.5} ... {"path": "/tmp/d/song.mp3", "title": "t", "artist": "a"}
+//
+// a response with an error field fails the request
+type pluginEngine struct {
+	name         string
+	path         string
+	args         []string
+	maxResults   int
+	capabilities Capabilities
+}
+
+type pluginRequest struct {
+	Action     string `json:"action"`
+	Query      string `json:"query,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	MaxResults int    `json:"max_results,omitempty"`
+	Url        string `json:"url,omitempty"`
+	Dir        string `json:"dir,omitempty"`
+}
+
+type pluginResult struct {
+	Title    string  `json:"title"`
+	Url      string  `json:"url"`
+	Duration float64 `json:"duration"` // seconds
+	Artist   string  `json:"artist"`
+	Album    string  `json:"album"`
+}
+
+type pluginResponse struct {
+	Error        string         `json:"error"`
+	Progress     *float64       `json:"progress"`
+	Speed        string         `json:"speed"`
+	ETA          string         `json:"eta"`
+	Capabilities []string       `json:"capabilities"`
+	Results      []pluginResult `json:"results"`
+	Exists       bool           `json:"exists"`
+	Path         string         `json:"path"`
+	Title        string         `json:"title"`
+	Artist       string         `json:"artist"`
+	Album        string         `json:"album"`
+	Duration     float64        `json:"duration"`
+	TrackNumber  int            `json:"track_number"`
+	Year         int            `json:"year"`
+}
+
+// NewPluginEngine creates the engine of the command of the config,
+// the command is asked for its capabilities
+func NewPluginEngine(name string, cfg config.EngineConfig) (*pluginEngine, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("no command in the config")
+	}
+	path, err := exec.LookPath(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	maxResults := cfg.MaxResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+	pe := &pluginEngine{
+		name:       name,
+		path:       path,
+		args:       cfg.Args,
+		maxResults: maxResults,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginStartTimeout)
+	defer cancel()
+	res, err := pe.call(ctx, pluginRequest{Action: "capabilities"}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("capabilities request failed: %w", err)
+	}
+	for _, capability := range res.Capabilities {
+		switch capability {
+		case "search":
+			pe.capabilities.Search = true
+		case "download":
+			pe.capabilities.Download = true
+		case "metadata":
+			pe.capabilities.Metadata = true
+		default:
+			logger.LogWarn(
+				"plugin",
+				name,
+				"has an unsupported capability",
+				capability,
+			)
+		}
+	}
+	return pe, nil
+}
+
+func (pe *pluginEngine) Name() string {
+	return pe.name
+}
+
+func (pe *pluginEngine) MaxResults() int {
+	return pe.maxResults
+}
+
+func (pe *pluginEngine) Capabilities() Capabilities {
+	return pe.capabilities
+}
+
+// call runs the command for the request, progress is called for each progress line
+func (pe *pluginEngine) call(
+	ctx context.Context,
+	req pluginRequest,
+	progress func(Progress),
+) (pluginResponse, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return pluginResponse{}, err
+	}
+	cmd := exec.CommandContext(
+		ctx,
+		pe.path,
+		// copy the args so the concurrent commands don't share them
+		append([]string{}, pe.args...)...,
+	)
+	cmd.WaitDelay = killDelay
+	killGroup(cmd)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stderr = logger.ERRORLogger.Writer()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return pluginResponse{}, err
+	}
+	logger.LogInfo("excuting command", cmd.Args, string(input))
+	if err := cmd.Start(); err != nil {
+		return pluginResponse{}, err
+	}
+
+	var last []byte
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var res pluginResponse
+		if err := json.Unmarshal(line, &res); err == nil && res.Progress != nil {
+			if progress != nil {
+				progress(Progress{
+					Percent: *res.Progress,
+					Speed:   res.Speed,
+					ETA:     res.ETA,
+				})
+			}
+			continue
+		}
+		last = append(last[:0], line...)
+	}
+	// drain what is left after a too long line so the command can exit
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return pluginResponse{}, err
+	}
+	if scanner.Err() != nil {
+		return pluginResponse{}, scanner.Err()
+	}
+	if last == nil {
+		return pluginResponse{}, errors.New("plugin sent no response")
+	}
+	var res pluginResponse
+	if err := json.Unmarshal(last, &res); err != nil {
+		return pluginResponse{}, fmt.Errorf("invalid plugin response: %w", err)
+	}
+	if res.Error != "" {
+		return pluginResponse{}, errors.New(res.Error)
+	}
+	return res, nil
+}
+
+func (pe *pluginEngine) Search(ctx context.Context, query string, offset, limit int) ([]shared.SearchResult, error) {
+	res, err := pe.call(
+		ctx,
+		pluginRequest{
+			Action:     "search",
+			Query:      query,
+			Offset:     offset,
+			MaxResults: limit,
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var results []shared.SearchResult
+	for _, result := range res.Results {
+		results = append(results, shared.SearchResult{
+			Title:       result.Title,
+			Destination: result.Url,
+			Type:        pe.name,
+			Duration:    time.Duration(result.Duration * float64(time.Second)),
+			Artist:      result.Artist,
+			Album:       result.Album,
+		})
+	}
+	return results, nil
+}
+
+func (pe *pluginEngine) Exists(ctx context.Context, url string) (bool, error) {
+	res, err := pe.call(
+		ctx,
+		pluginRequest{
+			Action: "exists",
+			Url:    url,
+		},
+		nil,
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.Exists, nil
+}
+
+// Download asks the command to write the song in a temporary directory
+// which is removed once the song is read
+func (pe *pluginEngine) Download(
+	ctx context.Context,
+	url string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	dir, err := os.MkdirTemp("", "retro-plugin-*")
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	res, err := pe.call(
+		ctx,
+		pluginRequest{
+			Action: "download",
+			Url:    url,
+			Dir:    dir,
+		},
+		progress,
+	)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	if res.Path == "" {
+		return nil, shared.MusicMeta{}, errors.New("plugin sent no file path")
+	}
+	data, err := os.ReadFile(res.Path)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), shared.MusicMeta{
+		Title:       res.Title,
+		Artist:      res.Artist,
+		Album:       res.Album,
+		Duration:    time.Duration(res.Duration * float64(time.Second)),
+		TrackNumber: res.TrackNumber,
+		Year:        res.Year,
+	}, nil
+}
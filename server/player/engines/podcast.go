@@ -0,0 +1,305 @@
+package engines
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/shared"
+)
+
+// maxFeedSize is the max size of a feed document
+const maxFeedSize = 16 << 20
+
+// podcastEngine fetches the podcast feeds and downloads their episodes,
+// it doesn't search, the episodes come from the feeds
+type podcastEngine struct {
+	client *http.Client
+}
+
+func NewPodcastEngine(_ config.EngineConfig) (*podcastEngine, error) {
+	return &podcastEngine{
+		// the episodes can be long, the contexts stop them
+		client: &http.Client{},
+	}, nil
+}
+
+func (pe *podcastEngine) Name() string {
+	return "podcast"
+}
+
+func (pe *podcastEngine) MaxResults() int {
+	return 0
+}
+
+func (pe *podcastEngine) Capabilities() Capabilities {
+	return Capabilities{
+		Download: true,
+		Metadata: true,
+	}
+}
+
+func (pe *podcastEngine) Search(_ context.Context, _ string, _, _ int) ([]shared.SearchResult, error) {
+	return nil, errors.New("podcast engine can't search")
+}
+
+// Exists is false, the episodes urls are only known from the feeds
+func (pe *podcastEngine) Exists(_ context.Context, _ string) (bool, error) {
+	return false, nil
+}
+
+// Download fetches the episode file, its title is the file name
+// unless the feed tells a better one
+func (pe *podcastEngine) Download(
+	ctx context.Context,
+	url string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	resp, err := httpGet(ctx, pe.client, url)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := readBody(resp, progress)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	name := path.Base(resp.Request.URL.Path)
+	meta := shared.MusicMeta{
+		Title: strings.TrimSuffix(name, path.Ext(name)),
+	}
+	return io.NopCloser(bytes.NewReader(data)), meta, nil
+}
+
+// Feed is a podcast feed with its episodes
+type Feed struct {
+	Title       string
+	Author      string
+	Description string
+	Episodes    []Episode
+}
+
+// Episode is an item of a feed with an audio enclosure
+type Episode struct {
+	Guid      string
+	Title     string
+	Url       string
+	Published time.Time
+	Duration  time.Duration
+}
+
+type rssEnclosure struct {
+	Url  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Guid      string       `xml:"guid"`
+	PubDate   string       `xml:"pubDate"`
+	Duration  string       `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+}
+
+type rssFeed struct {
+	Channel struct {
+		Title       string    `xml:"title"`
+		Description string    `xml:"description"`
+		Author      string    `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+		Items       []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	Id        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Links     []atomLink `xml:"link"`
+}
+
+type atomFeed struct {
+	Title    string `xml:"title"`
+	Subtitle string `xml:"subtitle"`
+	Author   struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// isAudioEnclosure tells if the enclosure type is audio, an empty type is accepted
+func isAudioEnclosure(mimeType string) bool {
+	return mimeType == "" || strings.HasPrefix(strings.ToLower(mimeType), "audio/")
+}
+
+// pubDateLayouts are the date formats found in the rss feeds
+var pubDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseItunesDuration parses "HH:MM:SS", "MM:SS" or a number of seconds
+func parseItunesDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	d, err := shared.StringToDuration(value)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+func feedDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// the feeds in other charsets are read as they are
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	decoder.Strict = false
+	return decoder
+}
+
+// feedRoot returns the name of the root element of the document
+func feedRoot(data []byte) (string, error) {
+	decoder := feedDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// ParseFeed reads a RSS or Atom feed, the items without audio enclosure are skipped
+func ParseFeed(data []byte) (Feed, error) {
+	root, err := feedRoot(data)
+	if err != nil {
+		return Feed{}, fmt.Errorf("invalid feed: %w", err)
+	}
+	switch root {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	default:
+		return Feed{}, fmt.Errorf("invalid feed: unknown root element %q", root)
+	}
+}
+
+func parseRSS(data []byte) (Feed, error) {
+	var rss rssFeed
+	if err := feedDecoder(data).Decode(&rss); err != nil {
+		return Feed{}, fmt.Errorf("invalid rss feed: %w", err)
+	}
+	feed := Feed{
+		Title:       strings.TrimSpace(rss.Channel.Title),
+		Author:      strings.TrimSpace(rss.Channel.Author),
+		Description: strings.TrimSpace(rss.Channel.Description),
+	}
+	for _, item := range rss.Channel.Items {
+		if item.Enclosure.Url == "" || !isAudioEnclosure(item.Enclosure.Type) {
+			continue
+		}
+		guid := strings.TrimSpace(item.Guid)
+		if guid == "" {
+			guid = item.Enclosure.Url
+		}
+		feed.Episodes = append(feed.Episodes, Episode{
+			Guid:      guid,
+			Title:     strings.TrimSpace(item.Title),
+			Url:       strings.TrimSpace(item.Enclosure.Url),
+			Published: parseFeedDate(item.PubDate),
+			Duration:  parseItunesDuration(item.Duration),
+		})
+	}
+	return feed, nil
+}
+
+func parseAtom(data []byte) (Feed, error) {
+	var atom atomFeed
+	if err := feedDecoder(data).Decode(&atom); err != nil {
+		return Feed{}, fmt.Errorf("invalid atom feed: %w", err)
+	}
+	feed := Feed{
+		Title:       strings.TrimSpace(atom.Title),
+		Author:      strings.TrimSpace(atom.Author.Name),
+		Description: strings.TrimSpace(atom.Subtitle),
+	}
+	for _, entry := range atom.Entries {
+		var url string
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" && isAudioEnclosure(link.Type) {
+				url = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+		if url == "" {
+			continue
+		}
+		guid := strings.TrimSpace(entry.Id)
+		if guid == "" {
+			guid = url
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		feed.Episodes = append(feed.Episodes, Episode{
+			Guid:      guid,
+			Title:     strings.TrimSpace(entry.Title),
+			Url:       url,
+			Published: parseFeedDate(published),
+		})
+	}
+	return feed, nil
+}
+
+// FetchFeed downloads and parses the feed of the url
+func (pe *podcastEngine) FetchFeed(ctx context.Context, url string) (Feed, error) {
+	resp, err := httpGet(ctx, pe.client, url)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return Feed{}, err
+	}
+	return ParseFeed(data)
+}
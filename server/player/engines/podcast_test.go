@@ -0,0 +1,188 @@
+package engines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+)
+
+const rssFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title> Retro Talks </title>
+    <description>Talks about music</description>
+    <itunes:author>Retro</itunes:author>
+    <item>
+      <title>First</title>
+      <guid>ep-1</guid>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate>
+      <itunes:duration>01:02:03</itunes:duration>
+      <enclosure url="http://example.com/1.mp3" type="audio/mpeg" length="1"/>
+    </item>
+    <item>
+      <title>Video</title>
+      <guid>ep-2</guid>
+      <enclosure url="http://example.com/2.mp4" type="video/mp4" length="1"/>
+    </item>
+    <item>
+      <title>No guid</title>
+      <itunes:duration>90</itunes:duration>
+      <enclosure url="http://example.com/3.mp3" type="audio/mpeg" length="1"/>
+    </item>
+    <item>
+      <title>No enclosure</title>
+      <guid>ep-4</guid>
+    </item>
+  </channel>
+</rss>`
+
+const atomFixture = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Retro Atom</title>
+  <subtitle>Atom episodes</subtitle>
+  <author><name>Retro</name></author>
+  <entry>
+    <id>urn:ep:1</id>
+    <title>First</title>
+    <published>2006-01-02T15:04:05Z</published>
+    <link rel="alternate" href="http://example.com/1.html"/>
+    <link rel="enclosure" href="http://example.com/1.ogg" type="audio/ogg"/>
+  </entry>
+  <entry>
+    <id>urn:ep:2</id>
+    <title>Video</title>
+    <link rel="enclosure" href="http://example.com/2.mp4" type="video/mp4"/>
+  </entry>
+  <entry>
+    <title>No id</title>
+    <updated>2006-01-03T15:04:05Z</updated>
+    <link rel="enclosure" href="http://example.com/3.mp3"/>
+  </entry>
+</feed>`
+
+func checkEpisodes(t *testing.T, got, want []Episode) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d episodes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Guid != want[i].Guid ||
+			got[i].Title != want[i].Title ||
+			got[i].Url != want[i].Url ||
+			!got[i].Published.Equal(want[i].Published) ||
+			got[i].Duration != want[i].Duration {
+			t.Errorf("episode %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseFeedRSS(t *testing.T) {
+	feed, err := ParseFeed([]byte(rssFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Retro Talks" || feed.Author != "Retro" || feed.Description != "Talks about music" {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+	checkEpisodes(t, feed.Episodes, []Episode{
+		{
+			Guid:      "ep-1",
+			Title:     "First",
+			Url:       "http://example.com/1.mp3",
+			Published: time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC),
+			Duration:  time.Hour + 2*time.Minute + 3*time.Second,
+		},
+		{
+			Guid:     "http://example.com/3.mp3",
+			Title:    "No guid",
+			Url:      "http://example.com/3.mp3",
+			Duration: 90 * time.Second,
+		},
+	})
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	feed, err := ParseFeed([]byte(atomFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Retro Atom" || feed.Author != "Retro" || feed.Description != "Atom episodes" {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+	checkEpisodes(t, feed.Episodes, []Episode{
+		{
+			Guid:      "urn:ep:1",
+			Title:     "First",
+			Url:       "http://example.com/1.ogg",
+			Published: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			Guid:      "http://example.com/3.mp3",
+			Title:     "No id",
+			Url:       "http://example.com/3.mp3",
+			Published: time.Date(2006, 1, 3, 15, 4, 5, 0, time.UTC),
+		},
+	})
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	for _, data := range []string{"", "not xml", "<html><body/></html>"} {
+		if _, err := ParseFeed([]byte(data)); err == nil {
+			t.Errorf("ParseFeed(%q) succeeded", data)
+		}
+	}
+}
+
+func TestParseItunesDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"10:00:00", 10 * time.Hour},
+		{"02:03", 2*time.Minute + 3*time.Second},
+		{"59:59", 59*time.Minute + 59*time.Second},
+		{"3723", time.Hour + 2*time.Minute + 3*time.Second},
+		{" 90 ", 90 * time.Second},
+		{"0", 0},
+		{"", 0},
+		{"soon", 0},
+	}
+	for _, test := range tests {
+		if got := parseItunesDuration(test.value); got != test.want {
+			t.Errorf("parseItunesDuration(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFetchFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(rssFixture))
+	}))
+	defer server.Close()
+
+	engine, err := NewPodcastEngine(config.EngineConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := engine.FetchFeed(context.Background(), server.URL+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Retro Talks" || len(feed.Episodes) != 2 {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+
+	if _, err := engine.FetchFeed(context.Background(), server.URL+"/missing.xml"); err == nil {
+		t.Error("FetchFeed of a missing feed succeeded")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package engines
+
+import "os/exec"
+
+// killGroup only kills the command itself on the systems without process groups
+func killGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package engines
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killGroup makes the canceled command kill its whole process group,
+// so the children of yt-dlp like ffmpeg die with it
+func killGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,200 @@
+package engines
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// maxRadioRedirects is how many .pls/.m3u radio playlists are followed to find the stream
+const maxRadioRedirects = 3
+
+// radioIdleTimeout is how long a station can send nothing before its stream is dropped
+const radioIdleTimeout = 20 * time.Second
+
+// radioEngine plays the internet radios and the other endless http audio streams,
+// they are played as they arrive and never downloaded
+type radioEngine struct {
+	client *http.Client
+}
+
+func NewRadioEngine(_ config.EngineConfig) (*radioEngine, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = radioIdleTimeout
+	return &radioEngine{
+		// no overall timeout, the streams never end, the contexts and
+		// the idle reads stop them
+		client: &http.Client{
+			Transport: transport,
+		},
+	}, nil
+}
+
+func (r *radioEngine) Name() string {
+	return "radio"
+}
+
+func (r *radioEngine) MaxResults() int {
+	return 0
+}
+
+func (r *radioEngine) Capabilities() Capabilities {
+	return Capabilities{
+		Stream:   true,
+		Metadata: true,
+	}
+}
+
+func (r *radioEngine) Search(_ context.Context, _ string, _, _ int) ([]shared.SearchResult, error) {
+	return nil, errors.New("radio engine can't search")
+}
+
+func (r *radioEngine) Download(
+	_ context.Context,
+	_ string,
+	_ func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	return nil, shared.MusicMeta{}, errors.New("radio streams can't be downloaded")
+}
+
+func (r *radioEngine) Exists(ctx context.Context, url string) (bool, error) {
+	return r.IsLive(ctx, url), nil
+}
+
+// isRadioPlaylist tells if the response is a .pls or .m3u radio playlist
+func isRadioPlaylist(resp *http.Response) bool {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	switch {
+	case strings.Contains(contentType, "scpls"),
+		strings.Contains(contentType, "mpegurl"):
+		return true
+	}
+	switch strings.ToLower(path.Ext(resp.Request.URL.Path)) {
+	case ".pls", ".m3u":
+		return !strings.HasPrefix(contentType, "audio/mpeg")
+	}
+	return false
+}
+
+// isLiveAudio tells if the response is an endless audio stream,
+// the files with a known length are left to the other engines
+func isLiveAudio(resp *http.Response) bool {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "audio/") && contentType != "application/ogg" {
+		return false
+	}
+	return resp.ContentLength < 0 || resp.Header.Get("icy-name") != "" || resp.Header.Get("icy-metaint") != ""
+}
+
+// parseRadioPlaylist returns the stream urls of a .pls or .m3u radio playlist
+func parseRadioPlaylist(body io.Reader) []string {
+	var urls []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		// pls lines are File1=http://...
+		if key, value, found := strings.Cut(line, "="); found && strings.HasPrefix(strings.ToLower(key), "file") {
+			line = strings.TrimSpace(value)
+		}
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			urls = append(urls, line)
+		}
+	}
+	return urls
+}
+
+// open connects to the stream of the url, following the radio playlists
+func (r *radioEngine) open(ctx context.Context, url string, redirects int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// ask for the ICY metadata to get the titles
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	if !isRadioPlaylist(resp) {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if redirects >= maxRadioRedirects {
+		return nil, errors.New("too many radio playlists")
+	}
+	for _, streamUrl := range parseRadioPlaylist(io.LimitReader(resp.Body, 1<<16)) {
+		stream, err := r.open(ctx, streamUrl, redirects+1)
+		if err != nil {
+			logger.LogWarn("Failed to open radio stream", streamUrl, err)
+			continue
+		}
+		return stream, nil
+	}
+	return nil, errors.New("no stream found in the radio playlist")
+}
+
+// IsLive tells if the url is a radio stream or a radio playlist
+func (r *radioEngine) IsLive(ctx context.Context, url string) bool {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return false
+	}
+	resp, err := r.open(ctx, url, 0)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return isLiveAudio(resp)
+}
+
+// OpenLive returns the audio of the stream without its ICY metadata,
+// the titles of the metadata are passed to onTitle, the stream fails
+// when the station sends nothing for radioIdleTimeout
+func (r *radioEngine) OpenLive(
+	ctx context.Context,
+	url string,
+	onTitle func(string),
+) (io.ReadCloser, LiveInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	resp, err := r.open(ctx, url, 0)
+	if err != nil {
+		cancel()
+		return nil, LiveInfo{}, err
+	}
+	if !isLiveAudio(resp) {
+		resp.Body.Close()
+		cancel()
+		return nil, LiveInfo{}, errors.New("not an audio stream")
+	}
+	info := LiveInfo{
+		Name:        strings.TrimSpace(resp.Header.Get("icy-name")),
+		ContentType: strings.ToLower(resp.Header.Get("Content-Type")),
+	}
+	body := newIdleReader(resp.Body, radioIdleTimeout, cancel)
+	metaint, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err != nil || metaint <= 0 {
+		return body, info, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		newIcyReader(body, metaint, onTitle),
+		body,
+	}, info, nil
+}
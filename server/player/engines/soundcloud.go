@@ -0,0 +1,187 @@
+package engines
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// soundcloudEngine searches and downloads the soundcloud tracks with yt-dlp,
+// it needs no account nor client id
+type soundcloudEngine struct {
+	ytdlp
+	maxResults int
+}
+
+func NewSoundcloudEngine(cfg config.EngineConfig) (*soundcloudEngine, error) {
+	y, err := newYtdlp(cfg)
+	if err != nil {
+		return nil, err
+	}
+	maxResults := cfg.MaxResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+	return &soundcloudEngine{
+		ytdlp:      y,
+		maxResults: maxResults,
+	}, nil
+}
+
+func (sc *soundcloudEngine) Name() string {
+	return "soundcloud"
+}
+
+func (sc *soundcloudEngine) MaxResults() int {
+	return sc.maxResults
+}
+
+func (sc *soundcloudEngine) Capabilities() Capabilities {
+	return Capabilities{
+		Search:   true,
+		Download: true,
+		Stream:   true,
+		Playlist: true,
+		Metadata: true,
+	}
+}
+
+// soundcloudPath returns the path segments of a soundcloud url, false for the other sites
+func soundcloudPath(rawUrl string) ([]string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, false
+	}
+	switch strings.TrimPrefix(u.Hostname(), "www.") {
+	case "soundcloud.com", "m.soundcloud.com", "on.soundcloud.com", "api.soundcloud.com":
+	default:
+		return nil, false
+	}
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments, true
+}
+
+// IsPlaylist tells if the url is a soundcloud set like soundcloud.com/<user>/sets/<name>
+func (sc *soundcloudEngine) IsPlaylist(_ context.Context, rawUrl string) bool {
+	segments, ok := soundcloudPath(rawUrl)
+	return ok && len(segments) >= 3 && segments[1] == "sets"
+}
+
+// Exists tells if the url is a soundcloud track, the short on.soundcloud.com links are resolved by yt-dlp
+func (sc *soundcloudEngine) Exists(ctx context.Context, rawUrl string) (bool, error) {
+	segments, ok := soundcloudPath(rawUrl)
+	if !ok {
+		return false, nil
+	}
+	// a track is <user>/<track>, the short links are a single code
+	isShortLink := strings.Contains(rawUrl, "on.soundcloud.com/")
+	if (len(segments) < 2 && !isShortLink) || sc.IsPlaylist(ctx, rawUrl) {
+		return false, nil
+	}
+	return sc.exists(ctx, rawUrl)
+}
+
+// titleFromPermalink makes a title from the last segment of a track url,
+// the flat search of yt-dlp doesn't always give the titles
+func titleFromPermalink(permalink string) string {
+	name := path.Base(strings.TrimSuffix(permalink, "/"))
+	return strings.ReplaceAll(name, "-", " ")
+}
+
+func (sc *soundcloudEngine) results(entries []ytdlpEntry) []shared.SearchResult {
+	var results []shared.SearchResult
+	for _, entry := range entries {
+		if entry.Url == "" {
+			continue
+		}
+		title := entry.Title
+		if title == "" {
+			title = titleFromPermalink(entry.Url)
+		}
+		results = append(results, shared.SearchResult{
+			Title:       title,
+			Destination: entry.Url,
+			Duration:    entry.duration(),
+			Type:        sc.Name(),
+			Artist:      entry.Uploader,
+		})
+	}
+	return results
+}
+
+func (sc *soundcloudEngine) Search(ctx context.Context, query string, offset, limit int) ([]shared.SearchResult, error) {
+	entries, err := sc.entries(
+		ctx,
+		"scsearch"+strconv.Itoa(offset+limit)+":"+query,
+		"--playlist-start", strconv.Itoa(offset+1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sc.results(entries), nil
+}
+
+func (sc *soundcloudEngine) ExpandPlaylist(ctx context.Context, setUrl string) ([]shared.SearchResult, error) {
+	entries, err := sc.entries(ctx, setUrl)
+	if err != nil {
+		return nil, err
+	}
+	return sc.results(entries), nil
+}
+
+func (sc *soundcloudEngine) Download(
+	ctx context.Context,
+	trackUrl string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	if _, ok := soundcloudPath(trackUrl); !ok {
+		return nil, shared.MusicMeta{}, errors.New("not a soundcloud url")
+	}
+	// soundcloud has no artist field, the uploader is the artist
+	meta, err := sc.meta(ctx, trackUrl, true)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	logger.LogInfo("Downloading", meta.Title, "from", trackUrl)
+
+	reader, err := sc.download(ctx, trackUrl, progress)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	return reader, meta, nil
+}
+
+// DownloadStream gives the audio of the track as yt-dlp downloads it
+func (sc *soundcloudEngine) DownloadStream(
+	ctx context.Context,
+	trackUrl string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	if _, ok := soundcloudPath(trackUrl); !ok {
+		return nil, shared.MusicMeta{}, errors.New("not a soundcloud url")
+	}
+	meta, err := sc.meta(ctx, trackUrl, true)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	logger.LogInfo("Streaming", meta.Title, "from", trackUrl)
+
+	reader, err := sc.stream(ctx, trackUrl, progress)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	return reader, meta, nil
+}
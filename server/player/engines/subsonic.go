@@ -0,0 +1,327 @@
+package engines
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// subsonicVersion is the version of the Subsonic API retro speaks,
+// Navidrome and the other servers accept it
+const subsonicVersion = "1.16.1"
+
+// subsonicEngine plays the library of a Subsonic server like Navidrome,
+// its songs are known by their stream url without the credentials
+// e.g. https://music.example.com/rest/stream?id=abc
+type subsonicEngine struct {
+	client     *http.Client
+	server     *url.URL
+	username   string
+	password   string
+	maxResults int
+}
+
+func NewSubsonicEngine(cfg config.EngineConfig) (*subsonicEngine, error) {
+	if cfg.Url == "" || cfg.Username == "" {
+		return nil, errors.New("no server url or username in the config")
+	}
+	server, err := url.Parse(strings.TrimSuffix(cfg.Url, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if server.Scheme != "http" && server.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server url: %s", cfg.Url)
+	}
+	maxResults := cfg.MaxResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+	return &subsonicEngine{
+		client:     &http.Client{},
+		server:     server,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		maxResults: maxResults,
+	}, nil
+}
+
+func (se *subsonicEngine) Name() string {
+	return "subsonic"
+}
+
+func (se *subsonicEngine) MaxResults() int {
+	return se.maxResults
+}
+
+func (se *subsonicEngine) Capabilities() Capabilities {
+	return Capabilities{
+		Search:   true,
+		Download: true,
+		Playlist: true,
+		Metadata: true,
+	}
+}
+
+// endpoint returns the url of the endpoint without any parameter
+func (se *subsonicEngine) endpoint(name string) *url.URL {
+	u := *se.server
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/rest/" + name
+	return &u
+}
+
+// auth returns the parameters authenticating the request with a salted token,
+// the password itself is never sent
+func (se *subsonicEngine) auth() url.Values {
+	salt := make([]byte, 8)
+	rand.Read(salt)
+	s := hex.EncodeToString(salt)
+	token := md5.Sum([]byte(se.password + s))
+	return url.Values{
+		"u": {se.username},
+		"t": {hex.EncodeToString(token[:])},
+		"s": {s},
+		"v": {subsonicVersion},
+		"c": {"retro"},
+		"f": {"json"},
+	}
+}
+
+func (se *subsonicEngine) request(name string, params url.Values) string {
+	u := se.endpoint(name)
+	query := se.auth()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+type subsonicSong struct {
+	Id       string `json:"id"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Duration int    `json:"duration"`
+	Track    int    `json:"track"`
+	Year     int    `json:"year"`
+}
+
+func (s subsonicSong) meta() shared.MusicMeta {
+	return shared.MusicMeta{
+		Title:       s.Title,
+		Artist:      s.Artist,
+		Album:       s.Album,
+		Duration:    time.Duration(s.Duration) * time.Second,
+		TrackNumber: s.Track,
+		Year:        s.Year,
+	}
+}
+
+type subsonicPlaylist struct {
+	Id        string         `json:"id"`
+	Name      string         `json:"name"`
+	SongCount int            `json:"songCount"`
+	Entry     []subsonicSong `json:"entry"`
+}
+
+// subsonicResponse holds the answers of the endpoints retro calls
+type subsonicResponse struct {
+	Status string `json:"status"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	SearchResult3 struct {
+		Song []subsonicSong `json:"song"`
+	} `json:"searchResult3"`
+	Song      subsonicSong `json:"song"`
+	Playlists struct {
+		Playlist []subsonicPlaylist `json:"playlist"`
+	} `json:"playlists"`
+	Playlist subsonicPlaylist `json:"playlist"`
+}
+
+func decodeSubsonic(r io.Reader) (subsonicResponse, error) {
+	var body struct {
+		Response subsonicResponse `json:"subsonic-response"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return subsonicResponse{}, err
+	}
+	if body.Response.Status != "ok" {
+		if body.Response.Error != nil {
+			return subsonicResponse{}, fmt.Errorf(
+				"subsonic error %d: %s",
+				body.Response.Error.Code,
+				body.Response.Error.Message,
+			)
+		}
+		return subsonicResponse{}, errors.New("subsonic request failed")
+	}
+	return body.Response, nil
+}
+
+// call calls the endpoint of the API
+func (se *subsonicEngine) call(ctx context.Context, name string, params url.Values) (subsonicResponse, error) {
+	resp, err := httpGet(ctx, se.client, se.request(name, params))
+	if err != nil {
+		return subsonicResponse{}, err
+	}
+	defer resp.Body.Close()
+	return decodeSubsonic(resp.Body)
+}
+
+// streamUrl is the url of the song, it is the key of the song in the cache
+func (se *subsonicEngine) streamUrl(id string) string {
+	u := se.endpoint("stream")
+	u.RawQuery = url.Values{"id": {id}}.Encode()
+	return u.String()
+}
+
+// idOf returns the id of the url of the endpoint on the server,
+// the endpoints may end with .view as in the old Subsonic clients
+func (se *subsonicEngine) idOf(rawUrl, name string) (string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host != se.server.Host {
+		return "", false
+	}
+	path := strings.TrimSuffix(u.Path, ".view")
+	if path != se.endpoint(name).Path {
+		return "", false
+	}
+	id := u.Query().Get("id")
+	return id, id != ""
+}
+
+func (se *subsonicEngine) Search(ctx context.Context, query string, offset, limit int) ([]shared.SearchResult, error) {
+	res, err := se.call(
+		ctx,
+		"search3",
+		url.Values{
+			"query":       {query},
+			"songCount":   {strconv.Itoa(limit)},
+			"songOffset":  {strconv.Itoa(offset)},
+			"artistCount": {"0"},
+			"albumCount":  {"0"},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var results []shared.SearchResult
+	for _, song := range res.SearchResult3.Song {
+		results = append(results, se.result(song))
+	}
+	return results, nil
+}
+
+func (se *subsonicEngine) result(song subsonicSong) shared.SearchResult {
+	return shared.SearchResult{
+		Title:       song.Title,
+		Destination: se.streamUrl(song.Id),
+		Type:        se.Name(),
+		Duration:    time.Duration(song.Duration) * time.Second,
+		Artist:      song.Artist,
+		Album:       song.Album,
+	}
+}
+
+// Exists tells if the url is a song of the server, the server isn't asked
+func (se *subsonicEngine) Exists(_ context.Context, songUrl string) (bool, error) {
+	_, ok := se.idOf(songUrl, "stream")
+	return ok, nil
+}
+
+func (se *subsonicEngine) Download(
+	ctx context.Context,
+	songUrl string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	id, ok := se.idOf(songUrl, "stream")
+	if !ok {
+		return nil, shared.MusicMeta{}, errors.New("not a song of the subsonic server")
+	}
+	res, err := se.call(ctx, "getSong", url.Values{"id": {id}})
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	logger.LogInfo("Downloading", res.Song.Title, "from", songUrl)
+
+	resp, err := httpGet(ctx, se.client, se.request("stream", url.Values{"id": {id}}))
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	defer resp.Body.Close()
+	// the errors are answered with a document instead of the audio
+	contentType := resp.Header.Get("Content-Type")
+	if strings.Contains(contentType, "json") || strings.Contains(contentType, "xml") {
+		_, err := decodeSubsonic(resp.Body)
+		if err == nil {
+			err = errors.New("subsonic server sent no audio")
+		}
+		return nil, shared.MusicMeta{}, err
+	}
+	data, err := readBody(resp, progress)
+	if err != nil {
+		return nil, shared.MusicMeta{}, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), res.Song.meta(), nil
+}
+
+// IsPlaylist tells if the url is a playlist of the server
+// e.g. https://music.example.com/rest/getPlaylist?id=abc
+func (se *subsonicEngine) IsPlaylist(_ context.Context, playlistUrl string) bool {
+	_, ok := se.idOf(playlistUrl, "getPlaylist")
+	return ok
+}
+
+func (se *subsonicEngine) ExpandPlaylist(ctx context.Context, playlistUrl string) ([]shared.SearchResult, error) {
+	id, ok := se.idOf(playlistUrl, "getPlaylist")
+	if !ok {
+		return nil, errors.New("not a playlist of the subsonic server")
+	}
+	res, err := se.call(ctx, "getPlaylist", url.Values{"id": {id}})
+	if err != nil {
+		return nil, err
+	}
+	var results []shared.SearchResult
+	for _, song := range res.Playlist.Entry {
+		results = append(results, se.result(song))
+	}
+	return results, nil
+}
+
+// Playlists returns the playlists of the server
+func (se *subsonicEngine) Playlists(ctx context.Context) ([]shared.RemotePlaylist, error) {
+	res, err := se.call(ctx, "getPlaylists", nil)
+	if err != nil {
+		return nil, err
+	}
+	var playlists []shared.RemotePlaylist
+	for _, playlist := range res.Playlists.Playlist {
+		u := se.endpoint("getPlaylist")
+		u.RawQuery = url.Values{"id": {playlist.Id}}.Encode()
+		playlists = append(playlists, shared.RemotePlaylist{
+			Name:   playlist.Name,
+			Url:    u.String(),
+			Engine: se.Name(),
+			Songs:  playlist.SongCount,
+		})
+	}
+	return playlists, nil
+}
@@ -0,0 +1,210 @@
+package engines
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/shared"
+)
+
+const (
+	subsonicUser     = "retro"
+	subsonicPassword = "s3cret-password"
+	subsonicAudio    = "ID3 not really an mp3"
+)
+
+const subsonicSongJson = `{
+  "id": "song-1",
+  "title": "Blue Monday",
+  "artist": "New Order",
+  "album": "Power, Corruption & Lies",
+  "duration": 448,
+  "track": 5,
+  "year": 1983
+}`
+
+var subsonicReplies = map[string]string{
+	"search3": `{"subsonic-response": {"status": "ok", "version": "1.16.1",
+    "searchResult3": {"song": [` + subsonicSongJson + `]}}}`,
+	"getSong": `{"subsonic-response": {"status": "ok", "version": "1.16.1",
+    "song": ` + subsonicSongJson + `}}`,
+	"getPlaylists": `{"subsonic-response": {"status": "ok", "version": "1.16.1",
+    "playlists": {"playlist": [{"id": "pl-1", "name": "Eighties", "songCount": 1}]}}}`,
+	"getPlaylist": `{"subsonic-response": {"status": "ok", "version": "1.16.1",
+    "playlist": {"id": "pl-1", "name": "Eighties", "songCount": 1, "entry": [` + subsonicSongJson + `]}}}`,
+}
+
+// subsonicServer answers the endpoints retro calls and checks the credentials of each request
+func subsonicServer(t *testing.T) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, subsonicPassword) {
+			t.Errorf("the password is sent in %s", r.URL)
+		}
+		query := r.URL.Query()
+		if query.Has("p") {
+			t.Errorf("the p parameter is sent in %s", r.URL)
+		}
+		salt, token := query.Get("s"), query.Get("t")
+		sum := md5.Sum([]byte(subsonicPassword + salt))
+		if query.Get("u") != subsonicUser || salt == "" || token != hex.EncodeToString(sum[:]) {
+			t.Errorf("invalid credentials in %s", r.URL)
+			w.Write([]byte(`{"subsonic-response": {"status": "failed",
+        "error": {"code": 40, "message": "Wrong username or password"}}}`))
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/rest/")
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		if name == "stream" {
+			if query.Get("id") != "song-1" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write([]byte(subsonicAudio))
+			return
+		}
+		reply, ok := subsonicReplies[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestSubsonic(t *testing.T, server *httptest.Server) *subsonicEngine {
+	engine, err := NewSubsonicEngine(config.EngineConfig{
+		Url:      server.URL + "/",
+		Username: subsonicUser,
+		Password: subsonicPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestSubsonicSearch(t *testing.T) {
+	server, _ := subsonicServer(t)
+	engine := newTestSubsonic(t, server)
+
+	results, err := engine.Search(context.Background(), "blue monday", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []shared.SearchResult{{
+		Title:       "Blue Monday",
+		Destination: server.URL + "/rest/stream?id=song-1",
+		Type:        "subsonic",
+		Duration:    448 * time.Second,
+		Artist:      "New Order",
+		Album:       "Power, Corruption & Lies",
+	}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got %+v, want %+v", results, want)
+	}
+
+	ok, _ := engine.Exists(context.Background(), results[0].Destination)
+	if !ok {
+		t.Errorf("%s isn't a song of the server", results[0].Destination)
+	}
+	ok, _ = engine.Exists(context.Background(), "https://elsewhere.example.com/rest/stream?id=song-1")
+	if ok {
+		t.Error("a song of another server exists")
+	}
+}
+
+func TestSubsonicDownload(t *testing.T) {
+	server, calls := subsonicServer(t)
+	engine := newTestSubsonic(t, server)
+
+	// the old clients call the endpoints with .view
+	body, meta, err := engine.Download(context.Background(), server.URL+"/rest/stream.view?id=song-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != subsonicAudio {
+		t.Errorf("got %q, want %q", data, subsonicAudio)
+	}
+	want := shared.MusicMeta{
+		Title:       "Blue Monday",
+		Artist:      "New Order",
+		Album:       "Power, Corruption & Lies",
+		Duration:    448 * time.Second,
+		TrackNumber: 5,
+		Year:        1983,
+	}
+	if meta != want {
+		t.Errorf("got %+v, want %+v", meta, want)
+	}
+	if !reflect.DeepEqual(*calls, []string{"getSong", "stream"}) {
+		t.Errorf("unexpected calls %v", *calls)
+	}
+}
+
+func TestSubsonicPlaylists(t *testing.T) {
+	server, _ := subsonicServer(t)
+	engine := newTestSubsonic(t, server)
+
+	playlists, err := engine.Playlists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlaylists := []shared.RemotePlaylist{{
+		Name:   "Eighties",
+		Url:    server.URL + "/rest/getPlaylist?id=pl-1",
+		Engine: "subsonic",
+		Songs:  1,
+	}}
+	if !reflect.DeepEqual(playlists, wantPlaylists) {
+		t.Fatalf("got %+v, want %+v", playlists, wantPlaylists)
+	}
+
+	if !engine.IsPlaylist(context.Background(), playlists[0].Url) {
+		t.Errorf("%s isn't a playlist", playlists[0].Url)
+	}
+	songs, err := engine.ExpandPlaylist(context.Background(), playlists[0].Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(songs) != 1 || songs[0].Destination != server.URL+"/rest/stream?id=song-1" || songs[0].Title != "Blue Monday" {
+		t.Errorf("unexpected songs %+v", songs)
+	}
+}
+
+func TestSubsonicError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"subsonic-response": {"status": "failed",
+      "error": {"code": 40, "message": "Wrong username or password"}}}`))
+	}))
+	defer server.Close()
+	engine := newTestSubsonic(t, server)
+
+	_, err := engine.Search(context.Background(), "blue monday", 0, 10)
+	if err == nil || !strings.Contains(err.Error(), "Wrong username or password") {
+		t.Errorf("got %v, want the error of the server", err)
+	}
+}
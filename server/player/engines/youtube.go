@@ -1,38 +1,23 @@
 package engines
 
 import (
+	"context"
 	"io"
-	"os"
-	"os/exec"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/Malwarize/retro/config"
 	"github.com/Malwarize/retro/logger"
 	"github.com/Malwarize/retro/shared"
 )
 
-func (yt *youtubeEngine) Exists(videoUrl string) (bool, error) {
-	cmd := exec.Command(
-		yt.ytdlpPath,
-		"--ies",
-		"all,-generic",
-		videoUrl,
-		"--skip-download",
-	)
-	cmd.Stderr = logger.ERRORLogger.Writer()
-	logger.LogInfo("excuting command", cmd.Args)
-	// yt-dlp --ies all,-generic https://www.youtube.com/watch?v=videoId
-	out, err := cmd.Output()
-	if err != nil {
-		return false, logger.LogError(
-			logger.GError(
-				"Check video existence failed",
-				err,
-			),
-			string(out),
-		)
+func (yt *youtubeEngine) Exists(ctx context.Context, videoUrl string) (bool, error) {
+	// yt-dlp knows soundcloud too, leave its urls to the soundcloud engine
+	if _, ok := soundcloudPath(videoUrl); ok {
+		return false, nil
 	}
-	return true, nil
+	return yt.ytdlp.exists(ctx, videoUrl)
 }
 
 func (yt *youtubeEngine) Name() string {
@@ -40,30 +25,47 @@ func (yt *youtubeEngine) Name() string {
 }
 
 type youtubeEngine struct {
-	ytdlpPath string
+	ytdlp
+	maxResults int
 }
 
-func NewYoutubeEngine() (*youtubeEngine, error) {
-	path := "yt-dlp"
-	absPath, err := exec.LookPath(path)
+func NewYoutubeEngine(cfg config.EngineConfig) (*youtubeEngine, error) {
+	y, err := newYtdlp(cfg)
 	if err != nil {
 		return nil, err
 	}
+	maxResults := cfg.MaxResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
 	return &youtubeEngine{
-		ytdlpPath: absPath,
+		ytdlp:      y,
+		maxResults: maxResults,
 	}, nil
 }
 
+func (yt *youtubeEngine) Capabilities() Capabilities {
+	return Capabilities{
+		Search:   true,
+		Download: true,
+		Stream:   true,
+		Playlist: true,
+		Metadata: true,
+	}
+}
+
 // Search why I used ytdlp instead of YouTube lib : because ytdlp doesn't need API key to search
-func (yt *youtubeEngine) Search(query string, maxResults int) ([]shared.SearchResult, error) {
-	cmd := exec.Command(
-		yt.ytdlpPath,
+func (yt *youtubeEngine) Search(ctx context.Context, query string, offset, limit int) ([]shared.SearchResult, error) {
+	// the search is a playlist of offset+limit videos, the first ones are skipped
+	cmd := yt.command(
+		ctx,
 		"--get-id",
 		"--get-title",
 		"--get-duration",
 		"--skip-download",
 		"--flat-playlist",
-		"ytsearch"+strconv.Itoa(maxResults)+":"+query,
+		"--playlist-start", strconv.Itoa(offset+1),
+		"ytsearch"+strconv.Itoa(offset+limit)+":"+query,
 	)
 	out, err := cmd.Output()
 	if err != nil {
@@ -101,76 +103,75 @@ func (yt *youtubeEngine) Search(query string, maxResults int) ([]shared.SearchRe
 	return results, nil
 }
 
-func (yt *youtubeEngine) getYoutubeTitleFromUrl(url string) (string, error) {
-	cmd := exec.Command(
-		yt.ytdlpPath, "--get-title", url,
-	)
-	out, err := cmd.Output()
+// IsPlaylist tells if the url is a youtube playlist, a video url
+// with a list parameter is still a video
+func (yt *youtubeEngine) IsPlaylist(_ context.Context, playlistUrl string) bool {
+	u, err := url.Parse(playlistUrl)
 	if err != nil {
-		return "", err
+		return false
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	if host != "youtube.com" && host != "music.youtube.com" && host != "m.youtube.com" {
+		return false
 	}
-	return string(out), nil
+	query := u.Query()
+	return query.Get("list") != "" && query.Get("v") == ""
 }
 
-func giveMeTempFileName() (string, error) {
-	tmpSongFile, err := os.CreateTemp("", "retro-youtube-*.mp3")
-	defer os.Remove(tmpSongFile.Name())
+func (yt *youtubeEngine) ExpandPlaylist(ctx context.Context, playlistUrl string) ([]shared.SearchResult, error) {
+	entries, err := yt.entries(ctx, playlistUrl)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	var results []shared.SearchResult
+	for _, entry := range entries {
+		results = append(results, shared.SearchResult{
+			Title:       entry.Title,
+			Destination: "https://www.youtube.com/watch?v=" + entry.Id,
+			Duration:    entry.duration(),
+			Type:        yt.Name(),
+		})
 	}
-	return tmpSongFile.Name(), nil
+	return results, nil
 }
 
-func (yt *youtubeEngine) Download(videoUrl string) (io.ReadCloser, string, error) {
-	videoTitle, err := yt.getYoutubeTitleFromUrl(videoUrl)
+func (yt *youtubeEngine) Download(
+	ctx context.Context,
+	videoUrl string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	meta, err := yt.meta(ctx, videoUrl, false)
 	if err != nil {
-		return nil, "", err
+		return nil, shared.MusicMeta{}, err
 	}
-	logger.LogInfo("Downloading", videoTitle, "from", videoUrl)
+	logger.LogInfo("Downloading", meta.Title, "from", videoUrl)
 
-	tmpSongFile, err := giveMeTempFileName()
+	reader, err := yt.download(ctx, videoUrl, progress)
 	if err != nil {
-		return nil, "", err
+		return nil, shared.MusicMeta{}, err
 	}
+	return reader, meta, nil
+}
 
-	//yt-dlp --extract-audio --audio-format mp3 --output "/tmp/f.mp3" --progress https://www.youtube.com/watch\?v\=-RijT8GW4yw0
-	cmd := exec.Command(
-		yt.ytdlpPath,
-		"--extract-audio",
-		"--audio-format",
-		"mp3",
-		"--no-warning",
-		"--output",
-		tmpSongFile,
-		videoUrl,
-	)
-	logger.LogInfo("excuting command", cmd.Args)
-	cmd.Stderr = logger.ERRORLogger.Writer()
-	out, err := cmd.Output()
+func (yt *youtubeEngine) MaxResults() int {
+	return yt.maxResults
+}
+
+// DownloadStream gives the audio of the video as yt-dlp downloads it
+func (yt *youtubeEngine) DownloadStream(
+	ctx context.Context,
+	videoUrl string,
+	progress func(Progress),
+) (io.ReadCloser, shared.MusicMeta, error) {
+	meta, err := yt.meta(ctx, videoUrl, false)
 	if err != nil {
-		return nil, "", err
+		return nil, shared.MusicMeta{}, err
 	}
+	logger.LogInfo("Streaming", meta.Title, "from", videoUrl)
 
-	logger.LogInfo("yt-dlp output:\n", string(out))
-
-	// fill the content in buffer and return it
-	buffer, err := os.ReadFile(tmpSongFile)
+	reader, err := yt.stream(ctx, videoUrl, progress)
 	if err != nil {
-		return nil, "", err
+		return nil, shared.MusicMeta{}, err
 	}
-
-	reader := io.NopCloser(strings.NewReader(string(buffer)))
-	// defer func() {
-	// 	tmpSongFile.Close()
-	// 	logger.LogInfo(
-	// 		"Removing tmp file", tmpSongFile.Name(),
-	// 	)
-	// 	os.Remove(tmpSongFile.Name())
-	// }()
-
-	return reader, videoTitle, nil
-}
-
-func (yt *youtubeEngine) MaxResults() int {
-	return 10
+	return reader, meta, nil
 }
@@ -0,0 +1,358 @@
+package engines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// killDelay is how long a killed yt-dlp has to release its output,
+// after it the pipes are closed so Wait returns even if a child process holds them
+const killDelay = 5 * time.Second
+
+// ytdlp runs the yt-dlp commands shared by the engines built on it
+type ytdlp struct {
+	path string
+	args []string
+}
+
+// newYtdlp finds yt-dlp at the engine path, or path_ytldpl when the engine has none
+func newYtdlp(cfg config.EngineConfig) (ytdlp, error) {
+	path := cfg.Path
+	if path == "" {
+		path = config.GetConfig().PathYTDL
+	}
+	absPath, err := exec.LookPath(path)
+	if err != nil {
+		return ytdlp{}, err
+	}
+	return ytdlp{
+		path: absPath,
+		args: cfg.Args,
+	}, nil
+}
+
+// command returns a yt-dlp command killed when ctx is done
+func (y ytdlp) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(
+		ctx,
+		y.path,
+		// copy the extra args so the concurrent commands don't share them
+		append(append([]string{}, y.args...), args...)...,
+	)
+	cmd.WaitDelay = killDelay
+	killGroup(cmd)
+	return cmd
+}
+
+// exists tells if an extractor other than the generic one knows the url
+func (y ytdlp) exists(ctx context.Context, url string) (bool, error) {
+	cmd := y.command(
+		ctx,
+		"--ies",
+		"all,-generic",
+		url,
+		"--skip-download",
+	)
+	cmd.Stderr = logger.ERRORLogger.Writer()
+	logger.LogInfo("excuting command", cmd.Args)
+	// yt-dlp --ies all,-generic https://www.youtube.com/watch?v=videoId
+	out, err := cmd.Output()
+	if err != nil {
+		return false, logger.LogError(
+			logger.GError(
+				"Check video existence failed",
+				err,
+			),
+			string(out),
+		)
+	}
+	return true, nil
+}
+
+// ytdlpMeta is the subset of the yt-dlp info dict printed by meta
+type ytdlpMeta struct {
+	Title       string  `json:"title"`
+	Artist      string  `json:"artist"`
+	Uploader    string  `json:"uploader"`
+	Album       string  `json:"album"`
+	Duration    float64 `json:"duration"`
+	TrackNumber int     `json:"track_number"`
+	ReleaseYear int     `json:"release_year"`
+}
+
+// meta returns the metadata of the url without downloading it,
+// the uploader is the artist when the site doesn't tell it
+func (y ytdlp) meta(ctx context.Context, url string, uploaderIsArtist bool) (shared.MusicMeta, error) {
+	cmd := y.command(
+		ctx,
+		"--skip-download",
+		"--no-warning",
+		"--print",
+		"%(.{title,artist,uploader,album,duration,track_number,release_year})j",
+		url,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return shared.MusicMeta{}, err
+	}
+	var meta ytdlpMeta
+	if err := json.Unmarshal(out, &meta); err != nil {
+		return shared.MusicMeta{}, err
+	}
+	artist := meta.Artist
+	if artist == "" && uploaderIsArtist {
+		artist = meta.Uploader
+	}
+	return shared.MusicMeta{
+		Title:       meta.Title,
+		Artist:      artist,
+		Album:       meta.Album,
+		Duration:    time.Duration(meta.Duration * float64(time.Second)),
+		TrackNumber: meta.TrackNumber,
+		Year:        meta.ReleaseYear,
+	}, nil
+}
+
+// ytdlpEntry is an entry of a flat playlist or search printed by entries
+type ytdlpEntry struct {
+	Id       string  `json:"id"`
+	Url      string  `json:"url"`
+	Title    string  `json:"title"`
+	Uploader string  `json:"uploader"`
+	Duration float64 `json:"duration"`
+}
+
+func (e ytdlpEntry) duration() time.Duration {
+	return time.Duration(e.Duration * float64(time.Second))
+}
+
+// entries lists the entries of a playlist url or a search like "ytsearch10:query"
+// without resolving each of them
+func (y ytdlp) entries(ctx context.Context, target string, args ...string) ([]ytdlpEntry, error) {
+	cmd := y.command(
+		ctx,
+		append(
+			[]string{
+				"--flat-playlist",
+				"--skip-download",
+				"--no-warning",
+				"--print",
+				"%(.{id,url,title,uploader,duration})j",
+				target,
+			},
+			args...,
+		)...,
+	)
+	cmd.Stderr = logger.ERRORLogger.Writer()
+	logger.LogInfo("excuting command", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ytdlpEntry
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var entry ytdlpEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.LogWarn("Invalid yt-dlp playlist entry", scanner.Text(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func giveMeTempFileName() (string, error) {
+	tmpSongFile, err := os.CreateTemp("", "retro-ytdlp-*.mp3")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpSongFile.Name())
+	tmpSongFile.Close()
+	return tmpSongFile.Name(), nil
+}
+
+// progressLine matches the progress lines of yt-dlp like
+// "[download]  45.2% of    3.45MiB at  512.00KiB/s ETA 00:04"
+var progressLine = regexp.MustCompile(
+	`^\[download\]\s+([\d.]+)%(?:.*?\s+at\s+(\S+))?(?:.*?\s+ETA\s+(\S+))?`,
+)
+
+// parseProgress returns the progress of a yt-dlp output line
+func parseProgress(line string) (Progress, bool) {
+	match := progressLine.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return Progress{}, false
+	}
+	percent, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return Progress{}, false
+	}
+	return Progress{
+		Percent: percent,
+		Speed:   match[2],
+		ETA:     match[3],
+	}, true
+}
+
+// download extracts the audio of the url as mp3, progress is called
+// for each progress line of yt-dlp
+func (y ytdlp) download(
+	ctx context.Context,
+	url string,
+	progress func(Progress),
+) (io.ReadCloser, error) {
+	tmpSongFile, err := giveMeTempFileName()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpSongFile)
+
+	//yt-dlp --extract-audio --audio-format mp3 --output "/tmp/f.mp3" --progress https://www.youtube.com/watch\?v\=-RijT8GW4yw0
+	cmd := y.command(
+		ctx,
+		"--extract-audio",
+		"--audio-format",
+		"mp3",
+		"--no-warning",
+		"--progress",
+		"--newline",
+		"--output",
+		tmpSongFile,
+		url,
+	)
+	logger.LogInfo("excuting command", cmd.Args)
+	cmd.Stderr = logger.ERRORLogger.Writer()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if p, ok := parseProgress(scanner.Text()); ok && progress != nil {
+			progress(p)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	// fill the content in buffer and return it
+	buffer, err := os.ReadFile(tmpSongFile)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(buffer)), nil
+}
+
+// streamReader is the audio written by yt-dlp on its stdout,
+// the read ending the audio fails when yt-dlp failed
+type streamReader struct {
+	io.ReadCloser
+	cmd      *exec.Cmd
+	cancel   context.CancelFunc
+	stderr   chan struct{} // closed once the stderr is read
+	waitOnce sync.Once
+	waitErr  error
+}
+
+func (sr *streamReader) wait() error {
+	sr.waitOnce.Do(func() {
+		<-sr.stderr
+		sr.waitErr = sr.cmd.Wait()
+	})
+	return sr.waitErr
+}
+
+func (sr *streamReader) Read(p []byte) (int, error) {
+	n, err := sr.ReadCloser.Read(p)
+	if err == io.EOF {
+		if waitErr := sr.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close kills yt-dlp if it is still downloading
+func (sr *streamReader) Close() error {
+	sr.cancel()
+	sr.wait()
+	return nil
+}
+
+// stream downloads the best audio of the url to stdout, progress is called
+// for each progress line of yt-dlp which goes to stderr with the audio on stdout
+func (y ytdlp) stream(
+	ctx context.Context,
+	url string,
+	progress func(Progress),
+) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	// yt-dlp -f bestaudio/best --output - https://www.youtube.com/watch\?v\=-RijT8GW4yw0
+	cmd := y.command(
+		ctx,
+		"--format",
+		"bestaudio/best",
+		"--no-warning",
+		"--no-part",
+		"--progress",
+		"--newline",
+		"--output",
+		"-",
+		url,
+	)
+	logger.LogInfo("excuting command", cmd.Args)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	sr := &streamReader{
+		ReadCloser: stdout,
+		cmd:        cmd,
+		cancel:     cancel,
+		stderr:     make(chan struct{}),
+	}
+	go func() {
+		defer close(sr.stderr)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if p, ok := parseProgress(scanner.Text()); ok {
+				if progress != nil {
+					progress(p)
+				}
+				continue
+			}
+			logger.ERRORLogger.Println(scanner.Text())
+		}
+	}()
+	return sr, nil
+}
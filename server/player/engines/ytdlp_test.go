@@ -0,0 +1,204 @@
+package engines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Malwarize/retro/shared"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want Progress
+		ok   bool
+	}{
+		{
+			line: "[download]   0.0% of    3.45MiB at  Unknown B/s ETA Unknown",
+			want: Progress{Percent: 0, Speed: "Unknown", ETA: "Unknown"},
+			ok:   true,
+		},
+		{
+			line: "[download]  45.2% of    3.45MiB at  512.00KiB/s ETA 00:04",
+			want: Progress{Percent: 45.2, Speed: "512.00KiB/s", ETA: "00:04"},
+			ok:   true,
+		},
+		{
+			line: "[download]  12.5% of ~   5.00MiB at    2.00MiB/s ETA 00:02 (frag 1/8)",
+			want: Progress{Percent: 12.5, Speed: "2.00MiB/s", ETA: "00:02"},
+			ok:   true,
+		},
+		{
+			line: "[download] 100% of    3.45MiB in 00:00:02 at 1.50MiB/s   ",
+			want: Progress{Percent: 100, Speed: "1.50MiB/s"},
+			ok:   true,
+		},
+		{
+			line: "[download]  99.9%",
+			want: Progress{Percent: 99.9},
+			ok:   true,
+		},
+		{line: "[download] Destination: /tmp/retro-ytdlp-1.webm"},
+		{line: "[youtube] Extracting URL: https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{line: "[ExtractAudio] Destination: /tmp/retro-ytdlp-1.mp3"},
+		{line: "[download] ..% of 3.45MiB"},
+		{line: ""},
+	}
+	for _, test := range tests {
+		got, ok := parseProgress(test.line)
+		if ok != test.ok || got != test.want {
+			t.Errorf("parseProgress(%q) = %+v, %v, want %+v, %v", test.line, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// fakeYtdlp returns a yt-dlp printing the output and saving its arguments,
+// one per line, in the returned file
+func fakeYtdlp(t *testing.T, output string, args ...string) (ytdlp, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake yt-dlp is a shell script")
+	}
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "output")
+	argsFile := filepath.Join(dir, "args")
+	if err := os.WriteFile(outputFile, []byte(output), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > '" + argsFile + "'\n" +
+		"cat '" + outputFile + "'\n"
+	path := filepath.Join(dir, "yt-dlp")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return ytdlp{path: path, args: args}, argsFile
+}
+
+func readArgs(t *testing.T, argsFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestYtdlpEntries(t *testing.T) {
+	// yt-dlp --flat-playlist --print "%(.{id,url,title,uploader,duration})j" ytsearch3:daft punk
+	output := `{"id": "5NV6Rdv1a3I", "url": "https://www.youtube.com/watch?v=5NV6Rdv1a3I", "title": "Daft Punk - Get Lucky", "uploader": "Daft Punk", "duration": 369.0}
+{"id": "gAjR4_CbPpQ", "url": "https://www.youtube.com/watch?v=gAjR4_CbPpQ", "title": "Daft Punk - One More Time", "uploader": "Daft Punk", "duration": 320.5}
+WARNING: [youtube] unable to extract the uploader
+{"id": "yca6UsllwYs", "url": "https://www.youtube.com/watch?v=yca6UsllwYs", "title": "Daft Punk - Around The World", "uploader": null, "duration": null}
+`
+	y, argsFile := fakeYtdlp(t, output, "--proxy", "socks5://127.0.0.1:1080")
+	entries, err := y.entries(context.Background(), "ytsearch3:daft punk", "--playlist-start", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ytdlpEntry{
+		{
+			Id:       "5NV6Rdv1a3I",
+			Url:      "https://www.youtube.com/watch?v=5NV6Rdv1a3I",
+			Title:    "Daft Punk - Get Lucky",
+			Uploader: "Daft Punk",
+			Duration: 369,
+		},
+		{
+			Id:       "gAjR4_CbPpQ",
+			Url:      "https://www.youtube.com/watch?v=gAjR4_CbPpQ",
+			Title:    "Daft Punk - One More Time",
+			Uploader: "Daft Punk",
+			Duration: 320.5,
+		},
+		{
+			Id:    "yca6UsllwYs",
+			Url:   "https://www.youtube.com/watch?v=yca6UsllwYs",
+			Title: "Daft Punk - Around The World",
+		},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+	if d := entries[1].duration(); d != 320*time.Second+500*time.Millisecond {
+		t.Errorf("got duration %v", d)
+	}
+
+	args := readArgs(t, argsFile)
+	wantArgs := []string{
+		"--proxy", "socks5://127.0.0.1:1080",
+		"--flat-playlist", "--skip-download", "--no-warning",
+		"--print", "%(.{id,url,title,uploader,duration})j",
+		"ytsearch3:daft punk",
+		"--playlist-start", "1",
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("got args %q, want %q", args, wantArgs)
+	}
+}
+
+func TestYtdlpMeta(t *testing.T) {
+	tests := []struct {
+		name             string
+		output           string
+		uploaderIsArtist bool
+		want             shared.MusicMeta
+	}{
+		{
+			name:   "music",
+			output: `{"title": "Get Lucky", "artist": "Daft Punk", "uploader": "Daft Punk - Topic", "album": "Random Access Memories", "duration": 369.2, "track_number": 8, "release_year": 2013}`,
+			want: shared.MusicMeta{
+				Title:       "Get Lucky",
+				Artist:      "Daft Punk",
+				Album:       "Random Access Memories",
+				Duration:    369*time.Second + 200*time.Millisecond,
+				TrackNumber: 8,
+				Year:        2013,
+			},
+		},
+		{
+			name:   "video",
+			output: `{"title": "Get Lucky (live)", "artist": null, "uploader": "someone", "album": null, "duration": 400, "track_number": null, "release_year": null}`,
+			want: shared.MusicMeta{
+				Title:    "Get Lucky (live)",
+				Duration: 400 * time.Second,
+			},
+		},
+		{
+			name:             "uploader is the artist",
+			output:           `{"title": "Nightcall", "artist": null, "uploader": "Kavinsky", "album": null, "duration": 258, "track_number": null, "release_year": null}`,
+			uploaderIsArtist: true,
+			want: shared.MusicMeta{
+				Title:    "Nightcall",
+				Artist:   "Kavinsky",
+				Duration: 258 * time.Second,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			y, argsFile := fakeYtdlp(t, test.output+"\n")
+			meta, err := y.meta(context.Background(), "https://example.com/song", test.uploaderIsArtist)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta != test.want {
+				t.Errorf("got %+v, want %+v", meta, test.want)
+			}
+			args := readArgs(t, argsFile)
+			if args[len(args)-1] != "https://example.com/song" {
+				t.Errorf("the url isn't the last argument: %q", args)
+			}
+		})
+	}
+
+	y, _ := fakeYtdlp(t, "ERROR: Unsupported URL\n")
+	if _, err := y.meta(context.Background(), "https://example.com/song", false); err == nil {
+		t.Error("meta of an invalid output succeeded")
+	}
+}
@@ -0,0 +1,70 @@
+package player
+
+import (
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/shared"
+)
+
+// evictInterval is how often the cache limits are checked
+const evictInterval = 10 * time.Minute
+
+// EvictCache removes the least recently played musics that are not in a playlist
+// once the cache exceeds cache_max_size, and the ones older than cache_max_age,
+// the musics in the queue are kept
+func (p *Player) EvictCache() error {
+	cfg := config.GetConfig()
+	if cfg.CacheMaxSize == 0 && cfg.CacheMaxAge == 0 {
+		return nil
+	}
+	evicted, err := p.Director.Db.EvictMusics(
+		cfg.CacheMaxSize,
+		cfg.CacheMaxAge,
+		p.Queue.GetTitles(),
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to evict cache",
+				err,
+			),
+		)
+	}
+	if len(evicted) > 0 {
+		logger.LogInfo(
+			"Evicted from cache",
+			evicted,
+		)
+	}
+	return nil
+}
+
+// runCacheEvictor checks the cache limits periodically, it never returns
+func (p *Player) runCacheEvictor() {
+	for {
+		p.EvictCache()
+		time.Sleep(evictInterval)
+	}
+}
+
+func (p *Player) GetCacheUsage() (shared.CacheUsage, error) {
+	usage, err := p.Director.Db.GetCacheUsage()
+	if err != nil {
+		return shared.CacheUsage{}, logger.LogError(
+			logger.GError(
+				"Failed to get cache usage",
+				err,
+			),
+		)
+	}
+	return shared.CacheUsage{
+		Total:     usage.Total,
+		Evictable: usage.Evictable,
+		Sources:   usage.Sources,
+		Count:     usage.Count,
+		MaxSize:   config.GetConfig().CacheMaxSize,
+		MaxAge:    config.GetConfig().CacheMaxAge,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package player
+
+import (
+	"strings"
+
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	"github.com/Malwarize/retro/shared"
+)
+
+// ####################
+// # Library methods  #
+// ####################
+func (p *Player) LibraryArtists(args shared.LibraryArgs) ([]shared.LibraryArtist, error) {
+	artists, err := p.Director.Db.GetArtists(args)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get artists",
+				err,
+			),
+		)
+	}
+	var res []shared.LibraryArtist
+	for _, artist := range artists {
+		res = append(res, shared.LibraryArtist{
+			Name:   artist.Name,
+			Albums: artist.Albums,
+			Tracks: artist.Tracks,
+		})
+	}
+	return res, nil
+}
+
+func (p *Player) LibraryAlbums(args shared.LibraryArgs) ([]shared.LibraryAlbum, error) {
+	albums, err := p.Director.Db.GetAlbums(args)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get albums",
+				err,
+			),
+		)
+	}
+	var res []shared.LibraryAlbum
+	for _, album := range albums {
+		res = append(res, shared.LibraryAlbum{
+			Name:     album.Name,
+			Artist:   album.Artist,
+			Year:     album.Year,
+			Tracks:   album.Tracks,
+			Duration: album.Duration,
+		})
+	}
+	return res, nil
+}
+
+func (p *Player) LibraryTracks(args shared.LibraryArgs) ([]shared.LibraryTrack, error) {
+	tracks, err := p.Director.Db.GetTracks(args)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get tracks",
+				err,
+			),
+		)
+	}
+	var res []shared.LibraryTrack
+	for _, track := range tracks {
+		res = append(res, shared.LibraryTrack{
+			Name:   track.Name,
+			Hash:   track.Hash,
+			Source: track.Source,
+			Meta:   track.Meta(),
+		})
+	}
+	return res, nil
+}
+
+// LibraryPlay enqueues every track of the album and/or the artist
+func (p *Player) LibraryPlay(args shared.LibraryArgs) error {
+	var ms []db.Music
+	var err error
+	switch {
+	case args.Album != "":
+		ms, err = p.Director.Db.GetMusicsByAlbum(args.Album)
+	case args.Artist != "":
+		ms, err = p.Director.Db.GetMusicsByArtist(args.Artist)
+	default:
+		return logger.LogError(
+			logger.GError(
+				"Album or artist required",
+			),
+		)
+	}
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to get musics from library",
+				err,
+			),
+		)
+	}
+
+	enqueued := 0
+	for _, song := range ms {
+		if args.Album != "" && args.Artist != "" &&
+			!strings.EqualFold(song.Artist, args.Artist) {
+			continue
+		}
+		m, err := NewMusicFromDb(song)
+		if err != nil {
+			logger.LogWarn(
+				"skiping music",
+				song.Name,
+				err,
+			)
+			continue
+		}
+		p.Queue.Enqueue(
+			*m,
+		)
+		enqueued++
+	}
+	if enqueued == 0 {
+		return logger.LogError(
+			logger.GError(
+				"No music found in library",
+			),
+		)
+	}
+
+	if p.getPlayerState() == shared.Stopped {
+		err := p.Play()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
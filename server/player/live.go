@@ -0,0 +1,338 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
+	"github.com/gopxl/beep/mp3"
+
+	"github.com/Malwarize/retro/logger"
+	en "github.com/Malwarize/retro/server/player/engines"
+	"github.com/Malwarize/retro/shared"
+)
+
+const (
+	liveChunkSize = 16 * 1024
+	// liveChunks is how many chunks of a live stream are read ahead
+	liveChunks = 32
+	// liveLowWater is how much must be read ahead to decode without waiting,
+	// the speaker asks for 0.1s, about 4KB at 320k
+	liveLowWater = 16 * 1024
+	// liveRebuffer is how much is read ahead before playing again after it ran out
+	liveRebuffer = 4 * liveChunkSize
+)
+
+var errLiveSeek = errors.New("live streams can't seek")
+
+// prefetchReader reads the stream ahead in a goroutine,
+// so the network hiccups don't stall the speaker
+type prefetchReader struct {
+	src    io.ReadCloser
+	chunks chan []byte
+	buf    []byte
+	err    error // set before chunks is closed
+	done   chan struct{}
+	once   sync.Once
+
+	mu       sync.Mutex
+	buffered int  // bytes read ahead and not read yet
+	ended    bool // the stream ended, what is buffered is all there is
+}
+
+// available returns how many bytes can be read without waiting for the network,
+// and if the stream ended
+func (pr *prefetchReader) available() (int, bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return pr.buffered, pr.ended
+}
+
+func newPrefetchReader(src io.ReadCloser, chunks int) *prefetchReader {
+	pr := &prefetchReader{
+		src:    src,
+		chunks: make(chan []byte, chunks),
+		done:   make(chan struct{}),
+	}
+	go pr.fill()
+	return pr
+}
+
+func (pr *prefetchReader) fill() {
+	defer func() {
+		pr.mu.Lock()
+		pr.ended = true
+		pr.mu.Unlock()
+		close(pr.chunks)
+	}()
+	for {
+		chunk := make([]byte, liveChunkSize)
+		n, err := pr.src.Read(chunk)
+		if n > 0 {
+			select {
+			case pr.chunks <- chunk[:n]:
+				pr.mu.Lock()
+				pr.buffered += n
+				pr.mu.Unlock()
+			case <-pr.done:
+				pr.err = io.ErrClosedPipe
+				return
+			}
+		}
+		if err != nil {
+			pr.err = err
+			return
+		}
+	}
+}
+
+func (pr *prefetchReader) Read(p []byte) (int, error) {
+	if len(pr.buf) == 0 {
+		chunk, ok := <-pr.chunks
+		if !ok {
+			return 0, pr.err
+		}
+		pr.buf = chunk
+	}
+	n := copy(p, pr.buf)
+	pr.buf = pr.buf[n:]
+	pr.mu.Lock()
+	pr.buffered -= n
+	pr.mu.Unlock()
+	return n, nil
+}
+
+// Close stops the goroutine and closes the stream
+func (pr *prefetchReader) Close() error {
+	pr.once.Do(func() {
+		close(pr.done)
+	})
+	return pr.src.Close()
+}
+
+// liveConn is a connection to a live stream
+type liveConn struct {
+	decoder   beep.StreamSeekCloser
+	prefetch  *prefetchReader
+	cancel    context.CancelFunc
+	ended     bool
+	buffering bool // used by the speaker only, the read ahead ran out
+}
+
+// ready tells if the decoder can stream without waiting for the network,
+// after running out it waits for liveRebuffer to be read ahead
+func (c *liveConn) ready() bool {
+	buffered, ended := c.prefetch.available()
+	if ended {
+		// the decoder ends with what is left
+		return true
+	}
+	if c.buffering {
+		c.buffering = buffered < liveRebuffer
+		return !c.buffering
+	}
+	c.buffering = buffered < liveLowWater
+	return !c.buffering
+}
+
+func (c *liveConn) close() {
+	c.cancel()
+	c.decoder.Close()
+}
+
+// liveStream is the streamer of a live music, it is connected when the music is played
+// and disconnected when closed, it has no length and can't seek
+type liveStream struct {
+	Url   string
+	mu    sync.Mutex
+	conn  *liveConn
+	pos   int
+	title string // the ICY title of the stream
+}
+
+func (ls *liveStream) Stream(samples [][2]float64) (int, bool) {
+	ls.mu.Lock()
+	conn := ls.conn
+	ls.mu.Unlock()
+	// a closed stream plays silence until the speaker is cleared,
+	// ending it would skip to the next music, a stalled one plays
+	// silence until the network catches up
+	if conn == nil || !conn.ready() {
+		for i := range samples {
+			samples[i] = [2]float64{}
+		}
+		return len(samples), true
+	}
+	// the lock is not held while reading, Close must not wait for the network
+	n, ok := conn.decoder.Stream(samples)
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.pos += n
+	if !ok {
+		conn.ended = true
+	}
+	return n, ok
+}
+
+func (ls *liveStream) Err() error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.conn == nil {
+		return nil
+	}
+	return ls.conn.decoder.Err()
+}
+
+func (ls *liveStream) Len() int {
+	return 0
+}
+
+func (ls *liveStream) Position() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.pos
+}
+
+func (ls *liveStream) Seek(_ int) error {
+	return errLiveSeek
+}
+
+// Close disconnects the stream, it is connected again when played
+func (ls *liveStream) Close() error {
+	ls.mu.Lock()
+	conn := ls.conn
+	ls.conn = nil
+	ls.title = ""
+	ls.mu.Unlock()
+	if conn != nil {
+		conn.close()
+	}
+	return nil
+}
+
+// connected tells if the stream has a connection still playing
+func (ls *liveStream) connected() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.conn != nil && !ls.conn.ended
+}
+
+func (ls *liveStream) setTitle(title string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.title = title
+}
+
+// meta returns the metadata of the current track of the station,
+// the ICY titles are usually "Artist - Title"
+func (ls *liveStream) meta(station shared.MusicMeta) shared.MusicMeta {
+	ls.mu.Lock()
+	title := ls.title
+	ls.mu.Unlock()
+	if title == "" {
+		return station
+	}
+	meta := shared.MusicMeta{
+		Title: title,
+		Album: station.Title,
+	}
+	if artist, song, found := strings.Cut(title, " - "); found {
+		meta.Artist = strings.TrimSpace(artist)
+		meta.Title = strings.TrimSpace(song)
+	}
+	return meta
+}
+
+// openLive connects the live music if it isn't playing already
+func (p *Player) openLive(m *Music) (en.LiveInfo, error) {
+	ls := m.Live
+	if ls.connected() {
+		return en.LiveInfo{}, nil
+	}
+	// drop the ended connection
+	ls.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, info, err := p.Director.OpenLive(
+		ctx,
+		ls.Url,
+		ls.setTitle,
+	)
+	if err != nil {
+		cancel()
+		return info, err
+	}
+	prefetch := newPrefetchReader(reader, liveChunks)
+	decoder, format, err := mp3.Decode(prefetch)
+	if err != nil {
+		cancel()
+		prefetch.Close()
+		return info, err
+	}
+	ls.mu.Lock()
+	ls.conn = &liveConn{
+		decoder:  decoder,
+		prefetch: prefetch,
+		cancel:   cancel,
+	}
+	ls.pos = 0
+	ls.mu.Unlock()
+	m.Format = format
+	return info, nil
+}
+
+// closeLives disconnects the live musics of the queue except the one playing
+func (p *Player) closeLives(playing *Music) {
+	for i := 0; i < p.Queue.Size(); i++ {
+		m := p.Queue.GetMusicByIndex(i)
+		if m != nil && m.IsLive() && m.Live != playing.Live {
+			m.Live.Close()
+		}
+	}
+}
+
+// AddLive plays the live stream of the url, it is queued once
+func (p *Player) AddLive(url string) error {
+	for i := 0; i < p.Queue.Size(); i++ {
+		if m := p.Queue.GetMusicByIndex(i); m != nil && m.IsLive() && m.Live.Url == url {
+			p.Queue.SetCurrIndex(i)
+			return p.Play()
+		}
+	}
+
+	live := &liveStream{
+		Url: url,
+	}
+	music := &Music{
+		Name: url,
+		Meta: shared.MusicMeta{
+			Title: url,
+		},
+		Volume: &effects.Volume{
+			Streamer: live,
+			Base:     2,
+			Silent:   false,
+		},
+		Live: live,
+	}
+	info, err := p.openLive(music)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to open the live stream",
+				err,
+			),
+		)
+	}
+	if info.Name != "" {
+		music.Name = info.Name
+		music.Meta.Title = info.Name
+	}
+	p.Queue.Enqueue(*music)
+	p.Queue.SetCurrIndex(p.Queue.Size() - 1)
+	return p.Play()
+}
@@ -6,13 +6,45 @@ import (
 	"github.com/gopxl/beep"
 	"github.com/gopxl/beep/effects"
 	"github.com/gopxl/beep/speaker"
+
+	"github.com/Malwarize/retro/server/player/db"
+	"github.com/Malwarize/retro/shared"
 )
 
 type Music struct {
 	Name   string
+	Meta   shared.MusicMeta
 	Volume *effects.Volume
 	Format beep.Format
 	Data   []byte
+	Live   *liveStream // set for the live streams, they have no data
+	// set for the musics played while they download, they have no data until cached
+	Progressive *progressiveStream
+}
+
+// IsLive tells if the music is an endless stream
+func (m *Music) IsLive() bool {
+	return m.Live != nil
+}
+
+// IsDownloading tells if the music plays while it downloads and isn't cached yet
+func (m *Music) IsDownloading() bool {
+	return m.Progressive != nil && !m.Progressive.isCached()
+}
+
+// sameAs tells if the musics are the same, by their data or their stream url
+func (m *Music) sameAs(other *Music) bool {
+	if m.IsLive() || other.IsLive() {
+		return m.IsLive() && other.IsLive() && m.Live.Url == other.Live.Url
+	}
+	if m.Progressive != nil && other.Progressive != nil && m.Progressive.Url == other.Progressive.Url {
+		return true
+	}
+	// the downloading musics have no data yet
+	if len(m.Data) == 0 || len(other.Data) == 0 {
+		return false
+	}
+	return hash(m.Data) == hash(other.Data)
 }
 
 func NewMusic(
@@ -25,6 +57,9 @@ func NewMusic(
 	}
 	return &Music{
 		Name: name,
+		Meta: shared.MusicMeta{
+			Title: name,
+		},
 		Volume: &effects.Volume{
 			Streamer: streamer,
 			Base:     2,
@@ -35,6 +70,19 @@ func NewMusic(
 	}, nil
 }
 
+// NewMusicFromDb creates a music from a db row, keeping its metadata
+func NewMusicFromDb(m db.Music) (*Music, error) {
+	music, err := NewMusic(
+		m.Name,
+		m.Data,
+	)
+	if err != nil {
+		return nil, err
+	}
+	music.Meta = m.Meta()
+	return music, nil
+}
+
 func (m *Music) Streamer() beep.StreamSeekCloser {
 	return m.Volume.Streamer.(beep.StreamSeekCloser)
 }
@@ -1,6 +1,7 @@
 package player
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -32,6 +33,7 @@ type Player struct {
 	initialised bool
 	Director    *Director
 	Tasks       map[string]shared.Task
+	downloads   *downloadManager
 	Vol         uint8
 	_lmeta      lmeta
 	mu          sync.Mutex
@@ -51,6 +53,11 @@ func NewPlayer() *Player {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if !director.Db.FtsEnabled() {
+		logger.LogWarn(
+			"sqlite is built without fts5, library search falls back to LIKE",
+		)
+	}
 
 	return &Player{
 		Queue:       NewMusicQueue(),
@@ -60,6 +67,7 @@ func NewPlayer() *Player {
 		Director:    director,
 		Vol:         100,
 		Tasks:       make(map[string]shared.Task),
+		downloads:   newDownloadManager(config.GetConfig().MaxDownloads),
 	}
 }
 
@@ -106,6 +114,17 @@ func (p *Player) Play() error {
 		)
 	}
 
+	if music.IsLive() {
+		if _, err := p.openLive(music); err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to open the live stream",
+					err,
+				),
+			)
+		}
+	}
+
 	if !p.initialised {
 		err := speaker.Init(
 			music.Format.SampleRate,
@@ -124,9 +143,23 @@ func (p *Player) Play() error {
 			speaker.Clear()
 		}
 	}
+	// the live musics not played anymore stop downloading
+	p.closeLives(music)
 	p.setPlayerState(
 		shared.Playing,
 	)
+	// the downloading musics are not in the cache yet
+	if !music.IsLive() && !music.IsDownloading() {
+		if err := p.Director.Db.TouchMusic(music.Name); err != nil {
+			logger.LogWarn(
+				"Failed to update last play of",
+				music.Name,
+				err,
+			)
+		}
+		// the long musics start where they were left
+		p.restoreResume(music)
+	}
 	go func() {
 		done := make(
 			chan struct{},
@@ -192,13 +225,17 @@ func (p *Player) Next() error {
 			),
 		)
 	}
-	if err := currentMusic.SetPositionD(0); err != nil {
-		return logger.LogError(
-			logger.GError(
-				"Failed to set position",
-				err,
-			),
-		)
+	p.saveResume()
+	// the live musics can't rewind, they are disconnected by Play
+	if !currentMusic.IsLive() {
+		if err := currentMusic.SetPositionD(0); err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to set position",
+					err,
+				),
+			)
+		}
 	}
 	p.Queue.QueueNext()
 	err := p.Play()
@@ -238,13 +275,16 @@ func (p *Player) Prev() error {
 			),
 		)
 	}
-	if err := currentMusic.SetPositionD(0); err != nil {
-		return logger.LogError(
-			logger.GError(
-				"Failed to seek",
-				err,
-			),
-		)
+	p.saveResume()
+	if !currentMusic.IsLive() {
+		if err := currentMusic.SetPositionD(0); err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to seek",
+					err,
+				),
+			)
+		}
 	}
 	p.Queue.QueuePrev()
 	err := p.Play()
@@ -264,6 +304,7 @@ func (p *Player) Stop() error {
 	if state == shared.Paused {
 		p.Resume()
 	}
+	p.saveResume()
 	speaker.Clear()
 	p.Queue.Clear()
 	p.setPlayerState(
@@ -322,6 +363,13 @@ func (p *Player) Seek(d time.Duration) error {
 			),
 		)
 	}
+	if currentMusic.IsLive() {
+		return logger.LogError(
+			logger.GError(
+				"Can't seek a live stream",
+			),
+		)
+	}
 	if state == shared.Paused {
 		err := p.Resume()
 		if err != nil {
@@ -436,6 +484,13 @@ func (p *Player) CreatePlayList(plname string) error {
 			),
 		)
 	}
+	if _, err := p.Director.Db.GetSmartPlaylist(plname); err == nil {
+		return logger.LogError(
+			logger.GError(
+				"A smart playlist named " + plname + " already exists",
+			),
+		)
+	}
 	err = p.Director.Db.AddPlaylist(
 		plname,
 	)
@@ -494,6 +549,138 @@ func (p *Player) PlayListsNames() ([]string, error) {
 	return names, nil
 }
 
+// PlayLists returns the playlists with their description and size
+func (p *Player) PlayLists() ([]shared.PlayListInfo, error) {
+	lists, err := p.Director.Db.GetPlaylists()
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get playlists",
+				err,
+			),
+		)
+	}
+
+	var infos []shared.PlayListInfo
+	for _, list := range lists {
+		infos = append(infos, shared.PlayListInfo{
+			Name:        list.Name,
+			Description: list.Description,
+			Songs:       list.Songs,
+			CreatedAt:   list.CreatedAt,
+			UpdatedAt:   list.UpdatedAt,
+		})
+	}
+	return infos, nil
+}
+
+func (p *Player) RenamePlayList(plname, newName string) error {
+	pl, err := p.Director.Db.GetPlaylist(
+		plname,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Playlist does not exist",
+			),
+		)
+	}
+	err = p.Director.Db.RenamePlaylist(
+		pl.Name,
+		newName,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to rename playlist",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+func (p *Player) CopyPlayList(src, dst string) error {
+	pl, err := p.Director.Db.GetPlaylist(
+		src,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Playlist does not exist",
+			),
+		)
+	}
+	err = p.Director.Db.CopyPlaylist(
+		pl.Name,
+		dst,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to copy playlist",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+// MergePlayLists appends the songs of the playlists to into without duplicates
+func (p *Player) MergePlayLists(plnames []string, into string) error {
+	for _, plname := range plnames {
+		_, err := p.Director.Db.GetPlaylist(
+			plname,
+		)
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Playlist " + plname + " does not exist",
+				),
+			)
+		}
+	}
+	err := p.Director.Db.MergePlaylists(
+		plnames,
+		into,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to merge playlists",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+func (p *Player) DescribePlayList(plname, description string) error {
+	pl, err := p.Director.Db.GetPlaylist(
+		plname,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Playlist does not exist",
+			),
+		)
+	}
+	err = p.Director.Db.SetPlaylistDescription(
+		pl.Name,
+		description,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to set playlist description",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
 func (p *Player) RemoveMusicFromPlayList(plname string, music shared.IntOrString) error {
 	pl, err := p.Director.Db.GetPlaylist(
 		plname,
@@ -505,10 +692,18 @@ func (p *Player) RemoveMusicFromPlayList(plname string, music shared.IntOrString
 			),
 		)
 	}
-	var m *Music
 	ms, err := p.Director.Db.GetMusicsFromPlaylist(
 		pl.Name,
 	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to get songs from playlist",
+				err,
+			),
+		)
+	}
+	name := music.StrVal
 	if music.IsInt {
 		index := music.IntVal
 		if index < 0 || index >= len(ms) {
@@ -518,18 +713,12 @@ func (p *Player) RemoveMusicFromPlayList(plname string, music shared.IntOrString
 				),
 			)
 		}
-		err = p.Director.Db.RemoveMusicFromPlaylist(
-			pl.Name,
-			ms[index].Name,
-		)
-	} else {
-		name := music.StrVal
-		err = p.Director.Db.RemoveMusicFromPlaylist(
-			pl.Name,
-			name,
-		)
+		name = ms[index].Name
 	}
-
+	err = p.Director.Db.RemoveMusicFromPlaylist(
+		pl.Name,
+		name,
+	)
 	if err != nil {
 		return logger.LogError(
 			logger.GError(
@@ -540,43 +729,55 @@ func (p *Player) RemoveMusicFromPlayList(plname string, music shared.IntOrString
 	}
 
 	// check if the exists in the queue and remove it
-	for _, music := range p.Queue.queue {
-		if hash(music.Data) == hash(m.Data) {
-			p.Queue.Remove(
-				&music,
-			)
-		}
+	if p.Queue.GetMusicByName(name) != nil {
+		return p.Remove(
+			shared.IntOrString{
+				StrVal: name,
+			},
+		)
 	}
 	return nil
 }
 
-func (p *Player) GetPlayListMusicNames(plname string) ([]string, error) {
+// MoveMusicInPlayList moves the music at index from to index to in the playlist
+func (p *Player) MoveMusicInPlayList(plname string, from, to int) error {
 	pl, err := p.Director.Db.GetPlaylist(
 		plname,
 	)
 	if err != nil {
-		return nil, logger.LogError(
+		return logger.LogError(
 			logger.GError(
-				"Failed to get playlist",
-				err,
+				"Playlist does not exist",
 			),
 		)
 	}
-	songs, err := p.Director.Db.GetMusicsFromPlaylist(
+	err = p.Director.Db.MoveMusicInPlaylist(
 		pl.Name,
+		from,
+		to,
 	)
 	if err != nil {
-		return nil, logger.LogError(
+		return logger.LogError(
 			logger.GError(
-				"Failed to get songs from playlist",
+				"Failed to move song in playlist",
 				err,
 			),
 		)
 	}
+	return nil
+}
+
+func (p *Player) GetPlayListMusicNames(plname string) ([]string, error) {
+	songs, err := p.playlistMusics(
+		plname,
+	)
+	if err != nil {
+		return nil, err
+	}
 	var names []string
 	logger.LogInfo(
 		"Playlist name :",
-		pl.Name,
+		plname,
 	)
 	for _, song := range songs {
 		logger.LogInfo(
@@ -589,27 +790,11 @@ func (p *Player) GetPlayListMusicNames(plname string) ([]string, error) {
 }
 
 func (p *Player) PlayListPlayMusic(plname string, music shared.IntOrString) error {
-	pl, err := p.Director.Db.GetPlaylist(
+	ms, err := p.playlistMusics(
 		plname,
 	)
 	if err != nil {
-		return logger.LogError(
-			logger.GError(
-				"Failed to get playlist",
-				err,
-			),
-		)
-	}
-	ms, err := p.Director.Db.GetMusicsFromPlaylist(
-		pl.Name,
-	)
-	if err != nil {
-		return logger.LogError(
-			logger.GError(
-				"Failed to get musics from playlist",
-				err,
-			),
-		)
+		return err
 	}
 	var m *Music
 	if music.IsInt {
@@ -621,19 +806,13 @@ func (p *Player) PlayListPlayMusic(plname string, music shared.IntOrString) erro
 				),
 			)
 		}
-		m, err = NewMusic(
-			ms[index].Name,
-			ms[index].Data,
-		)
+		m, err = NewMusicFromDb(ms[index])
 
 	} else {
 		name := music.StrVal
 		for _, song := range ms {
 			if song.Name == name {
-				m, err = NewMusic(
-					song.Name,
-					song.Data,
-				)
+				m, err = NewMusicFromDb(song)
 			}
 		}
 	}
@@ -662,40 +841,22 @@ func (p *Player) PlayListPlayMusic(plname string, music shared.IntOrString) erro
 func (p *Player) PlayListPlayAll(
 	plname string,
 ) error {
-	pl, err := p.Director.Db.GetPlaylist(
+	ms, err := p.playlistMusics(
 		plname,
 	)
 	if err != nil {
-		return logger.LogError(
-			logger.GError(
-				"Failed to get playlist",
-				err,
-			),
-		)
-	}
-	ms, err := p.Director.Db.GetMusicsFromPlaylist(
-		pl.Name,
-	)
-	if err != nil {
-		return logger.LogError(
-			logger.GError(
-				"Failed to get musics from playlist",
-				err,
-			),
-		)
+		return err
 	}
 
 	for _, song := range ms {
-		m, err := NewMusic(
-			song.Name,
-			song.Data,
-		)
+		m, err := NewMusicFromDb(song)
 		if err != nil {
 			logger.LogWarn(
 				"skiping music",
 				song.Name,
 				err,
 			)
+			continue
 		}
 		p.Queue.Enqueue(
 			*m,
@@ -737,6 +898,10 @@ func (p *Player) GetCurrMusicDuration() time.Duration {
 	if music == nil {
 		return 0
 	}
+	// the live streams have no end
+	if music.IsLive() {
+		return 0
+	}
 	if p.getPlayerState() == shared.Paused {
 		return p._getlMeta()._lcurrentDur
 	}
@@ -788,21 +953,45 @@ func (p *Player) GetCachedMusics() ([]shared.NameHash, error) {
 	}
 	for _, music := range musics {
 		music_names = append(music_names, shared.NameHash{
-			Name: music.Name,
-			Hash: music.Hash,
+			Name:   music.Name,
+			Hash:   music.Hash,
+			Pinned: music.Pinned,
 		})
 	}
 	return music_names, nil
 }
 
 func (p *Player) GetPlayerStatus() shared.Status {
+	var meta shared.MusicMeta
+	live := false
+	if music := p.Queue.GetCurrMusic(); music != nil {
+		meta = music.Meta
+		if music.IsLive() {
+			meta = music.Live.meta(music.Meta)
+			live = true
+		}
+	}
 	return shared.Status{
 		CurrMusicIndex:    p.Queue.GetCurrIndex(),
 		CurrMusicPosition: p.GetCurrMusicPosition(),
 		CurrMusicDuration: p.GetCurrMusicDuration(),
+		CurrMusicMeta:     meta,
+		CurrMusicLive:     live,
 		PlayerState:       p.getPlayerState(),
 		MusicQueue:        p.Queue.GetTitles(),
 		Volume:            p.Vol,
 		Tasks:             p.Tasks,
 	}
 }
+
+// Engines returns the status of the engines from the config
+func (p *Player) Engines() []shared.EngineInfo {
+	return p.Director.Engines()
+}
+
+// RemotePlaylists returns the playlists of the servers of the engines
+func (p *Player) RemotePlaylists() []shared.RemotePlaylist {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+	defer cancel()
+	return p.Director.RemotePlaylists(ctx)
+}
@@ -0,0 +1,204 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	en "github.com/Malwarize/retro/server/player/engines"
+	"github.com/Malwarize/retro/shared"
+)
+
+// ##########################
+// # Playlist files methods #
+// ##########################
+
+// resolveEntry adds the song of a playlist file entry, the local paths are read
+// from the disk or the cache, the urls are downloaded by the engine that knows them,
+// when the location can't be used the song is matched by its metadata
+func (p *Player) resolveEntry(entry shared.PlayListEntry, how callback) error {
+	err := errors.New("no location")
+	if entry.Location != "" {
+		err = p.resolveLocation(entry.Location, how)
+		if err == nil {
+			return nil
+		}
+	}
+	if entry.Meta.Title == "" {
+		return err
+	}
+	logger.LogInfo(
+		"Matching",
+		entry.Meta.String(),
+		"by metadata because",
+		err,
+	)
+	return p.resolveMeta(entry.Meta, how)
+}
+
+func (p *Player) resolveLocation(location string, how callback) error {
+	if strings.Contains(location, "://") {
+		if engine, ok := p.Director.CachedEngine(location); ok {
+			return p.AddMusicFromOnline(
+				location,
+				engine,
+				how,
+			)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+		defer cancel()
+		for _, engine := range p.Director.EnginesWith(en.CanDownload) {
+			if ok, err := engine.Exists(ctx, location); err != nil || !ok {
+				continue
+			}
+			return p.AddMusicFromOnline(
+				location,
+				engine.Name(),
+				how,
+			)
+		}
+		return errors.New("no engine can download this url")
+	}
+
+	if _, err := os.Stat(location); err == nil {
+		return p.AddMusicFromFile(
+			location,
+			how,
+		)
+	}
+	// the file may be gone but still cached
+	m, err := p.Director.Db.GetMusic(
+		"local",
+		location,
+	)
+	if err != nil {
+		return errors.New("file not found")
+	}
+	return how(m)
+}
+
+// resolveMeta finds the song in the library by its title and artist,
+// then falls back to the first result of the engines
+func (p *Player) resolveMeta(meta shared.MusicMeta, how callback) error {
+	m, err := p.Director.Db.FindMusicByMeta(
+		meta.Title,
+		meta.Artist,
+		meta.Album,
+	)
+	if err == nil {
+		return how(m)
+	}
+
+	query := strings.TrimSpace(meta.Artist + " " + meta.Title)
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+	defer cancel()
+	for _, engine := range p.Director.EnginesWith(en.CanSearch) {
+		name := engine.Name()
+		results, err := p.Director.Search(
+			ctx,
+			name,
+			query,
+			0,
+			0,
+		)
+		if err != nil || len(results) == 0 {
+			logger.LogWarn(
+				"No result for",
+				query,
+				"in",
+				name,
+				err,
+			)
+			continue
+		}
+		return p.AddMusicFromOnline(
+			results[0].Destination,
+			results[0].Type,
+			how,
+		)
+	}
+	return errors.New("not found in library nor by the engines")
+}
+
+// ImportPlayList appends the entries to the playlist, creating it if needed,
+// the entries that couldn't be added are returned with the reason
+func (p *Player) ImportPlayList(
+	plname string,
+	entries []shared.PlayListEntry,
+) (shared.ImportPlayListReply, error) {
+	var reply shared.ImportPlayListReply
+	err := p.Director.Db.AddPlaylist(
+		plname,
+	)
+	if err != nil {
+		return reply, logger.LogError(
+			logger.GError(
+				"Failed to create playlist",
+				err,
+			),
+		)
+	}
+
+	addToPlaylist := p.playlistAdder(plname, db.EndOfPlaylist)
+	for _, entry := range entries {
+		logger.LogInfo(
+			"Importing",
+			entry.Location,
+			"to playlist",
+			plname,
+		)
+		err := p.resolveEntry(entry, addToPlaylist)
+		if err != nil {
+			logger.LogWarn(
+				"Failed to import",
+				entry.Location,
+				err,
+			)
+			reply.Unresolved = append(reply.Unresolved, shared.UnresolvedEntry{
+				PlayListEntry: entry,
+				Reason:        err.Error(),
+			})
+			continue
+		}
+		reply.Added++
+	}
+	return reply, nil
+}
+
+// ExportPlayList returns the songs of the playlist with their source key as location
+func (p *Player) ExportPlayList(plname string) ([]shared.PlayListEntry, error) {
+	pl, err := p.Director.Db.GetPlaylist(
+		plname,
+	)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Playlist does not exist",
+			),
+		)
+	}
+	ms, err := p.Director.Db.GetMusicsFromPlaylist(
+		pl.Name,
+	)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get songs from playlist",
+				err,
+			),
+		)
+	}
+	var entries []shared.PlayListEntry
+	for _, m := range ms {
+		entries = append(entries, shared.PlayListEntry{
+			Location: m.Key,
+			Hash:     m.Hash,
+			Meta:     m.Meta(),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,274 @@
+package player
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Malwarize/retro/config"
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	en "github.com/Malwarize/retro/server/player/engines"
+	"github.com/Malwarize/retro/shared"
+)
+
+// ###################
+// # Podcast methods #
+// ###################
+
+// fetchFeed reads the feed of the url within the search timeout
+func (p *Player) fetchFeed(url string) (en.Feed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().SearchTimeout)
+	defer cancel()
+	return p.Director.FetchFeed(ctx, url)
+}
+
+func (p *Player) saveFeed(podcast db.Podcast, feed en.Feed) error {
+	podcast.Title = feed.Title
+	podcast.Author = feed.Author
+	podcast.Description = feed.Description
+	var episodes []db.Episode
+	for _, episode := range feed.Episodes {
+		episodes = append(episodes, db.Episode{
+			Guid:      episode.Guid,
+			Title:     episode.Title,
+			Url:       episode.Url,
+			Published: episode.Published,
+			Duration:  episode.Duration,
+		})
+	}
+	return p.Director.Db.SaveEpisodes(podcast, episodes)
+}
+
+// AddPodcast follows the feed of the url, the podcast is named
+// after the title of the feed unless a name is given
+func (p *Player) AddPodcast(url, name string) error {
+	p.addTask(url, shared.Searching)
+	feed, err := p.fetchFeed(url)
+	if err != nil {
+		p.errorTask(url, err)
+		return logger.LogError(
+			logger.GError(
+				"Failed to read podcast feed",
+				err,
+			),
+		)
+	}
+	p.removeTask(url)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = feed.Title
+	}
+	if name == "" {
+		return logger.LogError(
+			logger.GError(
+				"The feed has no title, give the podcast a name",
+			),
+		)
+	}
+	podcast := db.Podcast{
+		Name: name,
+		Url:  url,
+	}
+	err = p.Director.Db.AddPodcast(podcast)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to add podcast",
+				err,
+			),
+		)
+	}
+	err = p.saveFeed(podcast, feed)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to save podcast episodes",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+// RefreshPodcast fetches the feed of the podcast again to get its new episodes,
+// all the podcasts are refreshed when the name is empty
+func (p *Player) RefreshPodcast(name string) error {
+	var podcasts []db.Podcast
+	if name == "" {
+		all, err := p.Director.Db.GetPodcasts()
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Failed to get podcasts",
+					err,
+				),
+			)
+		}
+		podcasts = all
+	} else {
+		podcast, err := p.Director.Db.GetPodcast(name)
+		if err != nil {
+			return logger.LogError(
+				logger.GError(
+					"Podcast does not exist",
+				),
+			)
+		}
+		podcasts = append(podcasts, podcast)
+	}
+
+	var failed error
+	for _, podcast := range podcasts {
+		feed, err := p.fetchFeed(podcast.Url)
+		if err == nil {
+			err = p.saveFeed(podcast, feed)
+		}
+		if err != nil {
+			// one broken feed must not stop the others
+			failed = logger.LogError(
+				logger.GError(
+					"Failed to refresh podcast "+podcast.Name,
+					err,
+				),
+			)
+		}
+	}
+	return failed
+}
+
+// runPodcastRefresher refreshes the podcasts every podcast_refresh unless offline, it never returns
+func (p *Player) runPodcastRefresher() {
+	for {
+		time.Sleep(config.GetConfig().PodcastRefresh)
+		if p.Director.Offline() {
+			continue
+		}
+		p.RefreshPodcast("")
+	}
+}
+
+func (p *Player) RemovePodcast(name string) error {
+	err := p.Director.Db.RemovePodcast(name)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to remove podcast",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+func (p *Player) Podcasts() ([]shared.PodcastInfo, error) {
+	podcasts, err := p.Director.Db.GetPodcasts()
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get podcasts",
+				err,
+			),
+		)
+	}
+	var res []shared.PodcastInfo
+	for _, podcast := range podcasts {
+		res = append(res, shared.PodcastInfo{
+			Name:        podcast.Name,
+			Url:         podcast.Url,
+			Title:       podcast.Title,
+			Author:      podcast.Author,
+			Description: podcast.Description,
+			Episodes:    podcast.Episodes,
+			RefreshedAt: podcast.RefreshedAt,
+		})
+	}
+	return res, nil
+}
+
+// PodcastEpisodes returns the episodes of the podcast, the newest first
+func (p *Player) PodcastEpisodes(name string) ([]shared.EpisodeInfo, error) {
+	if _, err := p.Director.Db.GetPodcast(name); err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Podcast does not exist",
+			),
+		)
+	}
+	episodes, err := p.Director.Db.GetEpisodes(name)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get podcast episodes",
+				err,
+			),
+		)
+	}
+	var res []shared.EpisodeInfo
+	for i, episode := range episodes {
+		res = append(res, shared.EpisodeInfo{
+			Index:     i,
+			Title:     episode.Title,
+			Url:       episode.Url,
+			Published: episode.Published,
+			Duration:  episode.Duration,
+			Cached:    episode.Cached,
+		})
+	}
+	return res, nil
+}
+
+// PlayPodcast downloads the episode at the index, 0 being the newest,
+// and plays it, the downloaded episodes are played from the cache
+func (p *Player) PlayPodcast(name string, index int) error {
+	podcast, err := p.Director.Db.GetPodcast(name)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Podcast does not exist",
+			),
+		)
+	}
+	episodes, err := p.Director.Db.GetEpisodes(name)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to get podcast episodes",
+				err,
+			),
+		)
+	}
+	if index < 0 || index >= len(episodes) {
+		return logger.LogError(
+			logger.GError(
+				"Episode index out of range",
+			),
+		)
+	}
+	episode := episodes[index]
+	meta := shared.MusicMeta{
+		Title:    episode.Title,
+		Artist:   podcast.Author,
+		Album:    podcast.Title,
+		Duration: episode.Duration,
+		Year:     episode.Published.Year(),
+	}
+	if episode.Published.IsZero() {
+		meta.Year = 0
+	}
+	go p.AddMusicFromOnlineWithMeta(
+		episode.Url,
+		"podcast",
+		meta,
+		func(m db.Music) error {
+			pmusic, err := NewMusicFromDb(m)
+			if err != nil {
+				return err
+			}
+			p.Queue.Enqueue(*pmusic)
+			p.Play()
+			return nil
+		},
+	)
+	return nil
+}
@@ -0,0 +1,364 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
+
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	en "github.com/Malwarize/retro/server/player/engines"
+	"github.com/Malwarize/retro/shared"
+)
+
+const (
+	// progressivePrebuffer is how much mp3 is downloaded before it plays,
+	// about 3 seconds at 192k, it is also how much more is needed to decode it
+	// again when the playback caught up with the download
+	progressivePrebuffer = 64 * 1024
+	// progressiveMargin is how many samples are kept away from the end of the
+	// downloaded part while downloading, its last frames may be incomplete
+	progressiveMargin    = 2 * 1152
+	progressiveChunkSize = 32 * 1024
+)
+
+var errNotBuffered = errors.New("nothing is downloaded yet")
+
+// progressiveStream is the streamer of a music played while it downloads, the download
+// decodes the mp3 downloaded so far and the speaker swaps the new decoder in when the
+// playback reaches the end of its own, the speaker never decodes and never waits,
+// the seeks can't go past what is downloaded
+type progressiveStream struct {
+	Url string
+
+	mu       sync.Mutex
+	data     []byte // only appended to, the decoded snapshots stay valid
+	finished bool   // the download ended, complete or not
+	cached   bool   // the download is in the cache
+	format   beep.Format
+	// the last decoder built by the download, until the speaker takes it
+	next         beep.StreamSeekCloser
+	nextComplete bool
+	built        int  // bytes of data of the last decoder built
+	builtAll     bool // the last decoder built has all the data
+	starving     bool // the speaker played all it has
+	warned       bool // the failure to decode was logged
+
+	// used by the speaker only
+	decoder  beep.StreamSeekCloser
+	complete bool // the decoder has all the data
+	pos      int
+	estimate int // samples of the duration reported by the engine
+}
+
+func (ps *progressiveStream) write(chunk []byte) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.data = append(ps.data, chunk...)
+}
+
+// finish ends the download, the music stops where the data stops
+func (ps *progressiveStream) finish() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.finished = true
+}
+
+func (ps *progressiveStream) size() int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return len(ps.data)
+}
+
+func (ps *progressiveStream) bytes() []byte {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.data
+}
+
+func (ps *progressiveStream) setCached() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.cached = true
+}
+
+func (ps *progressiveStream) isCached() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.cached
+}
+
+// build decodes the data downloaded so far for the speaker, it is called by the
+// download after each chunk, the data is decoded again once it grew by a quarter,
+// or by progressivePrebuffer when the speaker is starving, so the decoding stays
+// linear in the size of the download
+func (ps *progressiveStream) build() {
+	ps.mu.Lock()
+	data := ps.data
+	ended := ps.finished
+	grown := len(data) - ps.built
+	due := ps.built == 0 ||
+		ended ||
+		grown >= max(progressivePrebuffer, ps.built/4) ||
+		(ps.starving && grown >= progressivePrebuffer)
+	done := len(data) == 0 || (grown == 0 && (!ended || ps.builtAll))
+	ps.mu.Unlock()
+	if !due || done {
+		return
+	}
+
+	decoder, format, err := MusicDecode(data)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if err != nil {
+		// the first chunks may not hold a whole frame yet
+		if !ps.warned {
+			logger.LogWarn(
+				"Failed to decode",
+				ps.Url,
+				err,
+			)
+			ps.warned = true
+		}
+		return
+	}
+	ps.warned = false
+	if ps.next != nil {
+		ps.next.Close()
+	}
+	ps.next = decoder
+	ps.nextComplete = ended
+	ps.format = format
+	ps.built = len(data)
+	ps.builtAll = ended
+	ps.starving = false
+}
+
+// swap takes the last decoder built by the download, it only seeks it to the
+// position of the playback, false when the download built nothing new
+func (ps *progressiveStream) swap() bool {
+	ps.mu.Lock()
+	next, complete := ps.next, ps.nextComplete
+	ps.next = nil
+	if next == nil {
+		ps.starving = true
+	}
+	ps.mu.Unlock()
+	if next == nil {
+		return false
+	}
+	if err := next.Seek(min(ps.pos, next.Len())); err != nil {
+		next.Close()
+		return false
+	}
+	if ps.decoder != nil {
+		ps.decoder.Close()
+	}
+	ps.decoder = next
+	ps.complete = complete
+	return true
+}
+
+// available is how many samples can be played
+func (ps *progressiveStream) available() int {
+	if ps.decoder == nil {
+		return 0
+	}
+	if ps.complete {
+		return ps.decoder.Len()
+	}
+	return max(ps.decoder.Len()-progressiveMargin, 0)
+}
+
+func (ps *progressiveStream) Stream(samples [][2]float64) (int, bool) {
+	if ps.pos >= ps.available() {
+		ps.swap()
+	}
+	available := ps.available()
+	if ps.pos >= available {
+		if ps.complete {
+			return 0, false
+		}
+		// the download is behind, silence until more arrives
+		for i := range samples {
+			samples[i] = [2]float64{}
+		}
+		return len(samples), true
+	}
+	n, ok := ps.decoder.Stream(samples[:min(len(samples), available-ps.pos)])
+	ps.pos += n
+	if !ok && ps.complete {
+		return n, n > 0
+	}
+	return n, true
+}
+
+func (ps *progressiveStream) Err() error {
+	if ps.decoder == nil {
+		return nil
+	}
+	return ps.decoder.Err()
+}
+
+// Len is the duration reported by the engine until the download ends
+func (ps *progressiveStream) Len() int {
+	if ps.decoder == nil {
+		return ps.estimate
+	}
+	if ps.complete {
+		return ps.decoder.Len()
+	}
+	return max(ps.estimate, ps.decoder.Len())
+}
+
+func (ps *progressiveStream) Position() int {
+	return ps.pos
+}
+
+// Seek moves within what is downloaded, further seeks stop at its end
+func (ps *progressiveStream) Seek(p int) error {
+	if p > ps.available() {
+		ps.swap()
+	}
+	if ps.decoder == nil {
+		if p == 0 {
+			return nil
+		}
+		return errNotBuffered
+	}
+	p = max(min(p, ps.available()), 0)
+	if err := ps.decoder.Seek(p); err != nil {
+		return err
+	}
+	ps.pos = p
+	return nil
+}
+
+// Close releases the decoders
+func (ps *progressiveStream) Close() error {
+	if ps.decoder != nil {
+		ps.decoder.Close()
+	}
+	ps.decoder = nil
+	ps.complete = false
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.next != nil {
+		ps.next.Close()
+	}
+	ps.next = nil
+	return nil
+}
+
+// newProgressiveMusic creates the music of the downloading stream,
+// it is called by the download before the speaker has the stream
+func newProgressiveMusic(ps *progressiveStream, meta shared.MusicMeta) (*Music, error) {
+	ps.build()
+	ps.swap()
+	if ps.decoder == nil {
+		return nil, errNotBuffered
+	}
+	ps.mu.Lock()
+	format := ps.format
+	ps.mu.Unlock()
+	ps.estimate = format.SampleRate.N(meta.Duration)
+	name := meta.Title
+	if name == "" {
+		name = ps.Url
+	}
+	return &Music{
+		Name: name,
+		Meta: meta,
+		Volume: &effects.Volume{
+			Streamer: ps,
+			Base:     2,
+			Silent:   false,
+		},
+		Format:      format,
+		Progressive: ps,
+	}, nil
+}
+
+// streamDownload downloads the music with the engine streaming it, the music
+// is passed to onStream as soon as it can play, then cached once downloaded,
+// started tells if onStream got it
+func (p *Player) streamDownload(
+	ctx context.Context,
+	d *download,
+	progress func(en.Progress),
+) (cached *db.Music, started bool, err error) {
+	reader, meta, err := p.Director.DownloadStream(
+		ctx,
+		d.Engine,
+		d.Target,
+		d.meta,
+		progress,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer reader.Close()
+
+	ps := &progressiveStream{
+		Url: d.Target,
+	}
+	var music *Music
+	chunk := make([]byte, progressiveChunkSize)
+	for {
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			ps.write(chunk[:n])
+			if music != nil {
+				ps.build()
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the music ends where the data ends
+			ps.finish()
+			ps.build()
+			return nil, music != nil, err
+		}
+		if music == nil && ps.size() >= progressivePrebuffer {
+			music, err = newProgressiveMusic(ps, meta)
+			if err != nil {
+				// not playable yet, it is played once cached
+				logger.LogWarn(
+					"Failed to play",
+					d.Target,
+					"while downloading",
+					err,
+				)
+				continue
+			}
+			d.onStream(music)
+		}
+	}
+	ps.finish()
+	ps.build()
+
+	cached, err = p.Director.Cache(
+		d.Engine,
+		d.Target,
+		ps.bytes(),
+		meta,
+	)
+	if err != nil {
+		return nil, music != nil, err
+	}
+	ps.setCached()
+	// the queued music is now the cached one
+	p.Queue.Update(func(m *Music) {
+		if m.Progressive == ps {
+			m.Name = cached.Name
+			m.Data = cached.Data
+		}
+	})
+	return cached, music != nil, nil
+}
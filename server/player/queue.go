@@ -83,13 +83,22 @@ func (q *MusicQueue) Enqueue(music Music) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	for _, m := range q.queue {
-		if hash(m.Data) == hash(music.Data) {
+		if m.sameAs(&music) {
 			return
 		}
 	}
 	q.queue = append(q.queue, music)
 }
 
+// Update changes the musics of the queue under the lock
+func (q *MusicQueue) Update(change func(m *Music)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.queue {
+		change(&q.queue[i])
+	}
+}
+
 func (q *MusicQueue) Size() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
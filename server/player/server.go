@@ -87,19 +87,65 @@ func (p *Player) RPCGetPlayerStatus(_ int, reply *shared.Status) error {
 	return nil
 }
 
-func (p *Player) RPCDetectAndPlay(query string, reply *[]shared.SearchResult) error {
-	logger.LogInfo("RPCDetectAndPlay called with query :", query)
+func (p *Player) RPCDetectAndPlay(args shared.DetectArgs, reply *shared.DetectReply) error {
+	logger.LogInfo(
+		"RPCDetectAndPlay called with query :",
+		args.Query,
+		"source :",
+		args.Source,
+		"offset :",
+		args.Offset,
+		"limit :",
+		args.Limit,
+	)
 	var err error
-	*reply, err = p.DetectAndPlay(query)
+	*reply, err = p.DetectAndPlay(args)
 	logger.LogInfo("RPCDetectAndPlay done with reply :", *reply)
 	return err
 }
 
+func (p *Player) RPCSearch(args shared.SearchArgs, reply *[]shared.SearchResult) error {
+	logger.LogInfo(
+		"RPCSearch called with query :",
+		args.Query,
+		"local :",
+		args.Local,
+		"source :",
+		args.Source,
+		"offset :",
+		args.Offset,
+		"limit :",
+		args.Limit,
+	)
+	*reply = p.Search(args)
+	logger.LogInfo("RPCSearch done with reply :", *reply)
+	return nil
+}
+
+func (p *Player) RPCSearchSources(_ int, reply *[]string) error {
+	logger.LogInfo("RPCSearchSources called")
+	*reply = p.SearchSources()
+	logger.LogInfo("RPCSearchSources done with reply :", *reply)
+	return nil
+}
+
+func (p *Player) RPCAddSelected(args shared.SelectArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCAddSelected called with queries :",
+		args.Queries,
+		"playlist name :",
+		args.PlayListName,
+	)
+	err := p.AddSelected(args)
+	logger.LogInfo("RPCAddSelected done")
+	return err
+}
+
 func (p *Player) RPCPlayListsNames(_ int, reply *[]string) error {
-	logger.LogInfo("RPCPlayLists called")
+	logger.LogInfo("RPCPlayListsNames called")
 	var err error
 	*reply, err = p.PlayListsNames()
-	logger.LogInfo("RPCPlayLists done with reply :", *reply)
+	logger.LogInfo("RPCPlayListsNames done with reply :", *reply)
 	return err
 }
 
@@ -119,9 +165,122 @@ func (p *Player) RPCRemovePlayList(name string, reply *int) error {
 	return err
 }
 
+func (p *Player) RPCPlayLists(_ int, reply *[]shared.PlayListInfo) error {
+	logger.LogInfo("RPCPlayLists called")
+	var err error
+	*reply, err = p.PlayLists()
+	logger.LogInfo("RPCPlayLists done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCRenamePlayList(args shared.RenamePlayListArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCRenamePlayList called with name :",
+		args.PlayListName,
+		"new name :",
+		args.NewName,
+	)
+	err := p.RenamePlayList(args.PlayListName, args.NewName)
+	*reply = 1
+	logger.LogInfo("RPCRenamePlayList done")
+	return err
+}
+
+func (p *Player) RPCCopyPlayList(args shared.CopyPlayListArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCCopyPlayList called with source :",
+		args.Source,
+		"destination :",
+		args.Destination,
+	)
+	err := p.CopyPlayList(args.Source, args.Destination)
+	*reply = 1
+	logger.LogInfo("RPCCopyPlayList done")
+	return err
+}
+
+func (p *Player) RPCMergePlayLists(args shared.MergePlayListsArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCMergePlayLists called with names :",
+		args.PlayListNames,
+		"into :",
+		args.Into,
+	)
+	err := p.MergePlayLists(args.PlayListNames, args.Into)
+	*reply = 1
+	logger.LogInfo("RPCMergePlayLists done")
+	return err
+}
+
+func (p *Player) RPCDescribePlayList(args shared.DescribePlayListArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCDescribePlayList called with name :",
+		args.PlayListName,
+		"description :",
+		args.Description,
+	)
+	err := p.DescribePlayList(args.PlayListName, args.Description)
+	*reply = 1
+	logger.LogInfo("RPCDescribePlayList done")
+	return err
+}
+
+func (p *Player) RPCImportPlayList(
+	args shared.ImportPlayListArgs,
+	reply *shared.ImportPlayListReply,
+) error {
+	logger.LogInfo(
+		"RPCImportPlayList called with name :",
+		args.PlayListName,
+		"entries :",
+		len(args.Entries),
+	)
+	var err error
+	*reply, err = p.ImportPlayList(args.PlayListName, args.Entries)
+	logger.LogInfo("RPCImportPlayList done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCExportPlayList(name string, reply *[]shared.PlayListEntry) error {
+	logger.LogInfo("RPCExportPlayList called with name :", name)
+	var err error
+	*reply, err = p.ExportPlayList(name)
+	logger.LogInfo("RPCExportPlayList done")
+	return err
+}
+
+func (p *Player) RPCSmartPlayLists(_ int, reply *[]shared.SmartPlayList) error {
+	logger.LogInfo("RPCSmartPlayLists called")
+	var err error
+	*reply, err = p.SmartPlayLists()
+	logger.LogInfo("RPCSmartPlayLists done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCCreateSmartPlayList(args shared.SmartPlayListArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCCreateSmartPlayList called with name :",
+		args.Name,
+		"rule :",
+		args.Rule,
+	)
+	err := p.CreateSmartPlayList(args.Name, args.Rule)
+	*reply = 1
+	logger.LogInfo("RPCCreateSmartPlayList done")
+	return err
+}
+
+func (p *Player) RPCRemoveSmartPlayList(name string, reply *int) error {
+	logger.LogInfo("RPCRemoveSmartPlayList called with name :", name)
+	err := p.RemoveSmartPlayList(name)
+	*reply = 1
+	logger.LogInfo("RPCRemoveSmartPlayList done")
+	return err
+}
+
 func (p *Player) RPCDetectAndAddToPlayList(
 	args shared.AddToPlayListArgs,
-	reply *[]shared.SearchResult,
+	reply *shared.DetectReply,
 ) error {
 	logger.LogInfo(
 		"RPCDetectAndAddToPlayList called with query :",
@@ -130,7 +289,7 @@ func (p *Player) RPCDetectAndAddToPlayList(
 		args.PlayListName,
 	)
 	var err error
-	*reply, err = p.DetectAndAddToPlayList(args.PlayListName, args.Query)
+	*reply, err = p.DetectAndAddToPlayList(args)
 	logger.LogInfo("RPCDetectAndAddToPlayList done")
 	return err
 }
@@ -166,6 +325,24 @@ func (p *Player) RPCRemoveMusicFromPlayList(
 	return err
 }
 
+func (p *Player) RPCMoveMusicInPlayList(
+	args shared.MoveMusicInPlayListArgs,
+	reply *int,
+) error {
+	logger.LogInfo(
+		"RPCMoveMusicInPlayList called with name :",
+		args.PlayListName,
+		"from",
+		args.From,
+		"to",
+		args.To,
+	)
+	err := p.MoveMusicInPlayList(args.PlayListName, args.From, args.To)
+	*reply = 1
+	logger.LogInfo("RPCMoveMusicInPlayList done")
+	return err
+}
+
 func (p *Player) RPCPlayListPlayMusic(args shared.PlayListPlayMusicArgs, reply *int) error {
 	logger.LogInfo(
 		"RPCPlayListPlayMusic called with name :",
@@ -226,6 +403,205 @@ func (p *Player) RPCGetCachedMusics(_ int, reply *[]shared.NameHash) error {
 	return err
 }
 
+func (p *Player) RPCGetCacheUsage(_ int, reply *shared.CacheUsage) error {
+	logger.LogInfo("RPCGetCacheUsage called")
+	var err error
+	*reply, err = p.GetCacheUsage()
+	logger.LogInfo("RPCGetCacheUsage done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCRemoveCachedMusic(target string, reply *int) error {
+	logger.LogInfo("RPCRemoveCachedMusic called with target :", target)
+	err := p.RemoveCachedMusic(target)
+	*reply = 1
+	logger.LogInfo("RPCRemoveCachedMusic done")
+	return err
+}
+
+func (p *Player) RPCPinCachedMusic(args shared.PinArgs, reply *int) error {
+	logger.LogInfo("RPCPinCachedMusic called with target :", args.Target, "pin :", args.Pin)
+	err := p.PinCachedMusic(args.Target, args.Pin)
+	*reply = 1
+	logger.LogInfo("RPCPinCachedMusic done")
+	return err
+}
+
+func (p *Player) RPCExportCachedMusic(target string, reply *shared.MusicFile) error {
+	logger.LogInfo("RPCExportCachedMusic called with target :", target)
+	var err error
+	*reply, err = p.ExportCachedMusic(target)
+	logger.LogInfo("RPCExportCachedMusic done")
+	return err
+}
+
+func (p *Player) RPCVerifyCache(_ int, reply *[]shared.NameHash) error {
+	logger.LogInfo("RPCVerifyCache called")
+	var err error
+	*reply, err = p.VerifyCache()
+	logger.LogInfo("RPCVerifyCache done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCLibraryArtists(args shared.LibraryArgs, reply *[]shared.LibraryArtist) error {
+	logger.LogInfo("RPCLibraryArtists called with args :", args)
+	var err error
+	*reply, err = p.LibraryArtists(args)
+	logger.LogInfo("RPCLibraryArtists done")
+	return err
+}
+
+func (p *Player) RPCLibraryAlbums(args shared.LibraryArgs, reply *[]shared.LibraryAlbum) error {
+	logger.LogInfo("RPCLibraryAlbums called with args :", args)
+	var err error
+	*reply, err = p.LibraryAlbums(args)
+	logger.LogInfo("RPCLibraryAlbums done")
+	return err
+}
+
+func (p *Player) RPCLibraryTracks(args shared.LibraryArgs, reply *[]shared.LibraryTrack) error {
+	logger.LogInfo("RPCLibraryTracks called with args :", args)
+	var err error
+	*reply, err = p.LibraryTracks(args)
+	logger.LogInfo("RPCLibraryTracks done")
+	return err
+}
+
+func (p *Player) RPCLibraryPlay(args shared.LibraryArgs, reply *int) error {
+	logger.LogInfo("RPCLibraryPlay called with args :", args)
+	err := p.LibraryPlay(args)
+	*reply = 1
+	logger.LogInfo("RPCLibraryPlay done")
+	return err
+}
+
+func (p *Player) RPCDownloads(_ int, reply *[]shared.DownloadTask) error {
+	logger.LogInfo("RPCDownloads called")
+	*reply = p.Downloads()
+	logger.LogInfo("RPCDownloads done with reply :", *reply)
+	return nil
+}
+
+func (p *Player) RPCCancelDownload(id int, reply *int) error {
+	logger.LogInfo("RPCCancelDownload called with id :", id)
+	err := p.CancelDownload(id)
+	*reply = 1
+	logger.LogInfo("RPCCancelDownload done")
+	return err
+}
+
+func (p *Player) RPCRetryDownload(id int, reply *int) error {
+	logger.LogInfo("RPCRetryDownload called with id :", id)
+	err := p.RetryDownload(id)
+	*reply = 1
+	logger.LogInfo("RPCRetryDownload done")
+	return err
+}
+
+func (p *Player) RPCEngines(_ int, reply *[]shared.EngineInfo) error {
+	logger.LogInfo("RPCEngines called")
+	*reply = p.Engines()
+	logger.LogInfo("RPCEngines done with reply :", *reply)
+	return nil
+}
+
+func (p *Player) RPCRemotePlaylists(_ int, reply *[]shared.RemotePlaylist) error {
+	logger.LogInfo("RPCRemotePlaylists called")
+	*reply = p.RemotePlaylists()
+	logger.LogInfo("RPCRemotePlaylists done with reply :", *reply)
+	return nil
+}
+
+func (p *Player) RPCAddPodcast(args shared.AddPodcastArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCAddPodcast called with url :",
+		args.Url,
+		"name :",
+		args.Name,
+	)
+	err := p.AddPodcast(args.Url, args.Name)
+	*reply = 1
+	logger.LogInfo("RPCAddPodcast done")
+	return err
+}
+
+func (p *Player) RPCPodcasts(_ int, reply *[]shared.PodcastInfo) error {
+	logger.LogInfo("RPCPodcasts called")
+	var err error
+	*reply, err = p.Podcasts()
+	logger.LogInfo("RPCPodcasts done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCPodcastEpisodes(name string, reply *[]shared.EpisodeInfo) error {
+	logger.LogInfo("RPCPodcastEpisodes called with name :", name)
+	var err error
+	*reply, err = p.PodcastEpisodes(name)
+	logger.LogInfo("RPCPodcastEpisodes done")
+	return err
+}
+
+func (p *Player) RPCPodcastPlay(args shared.PodcastPlayArgs, reply *int) error {
+	logger.LogInfo(
+		"RPCPodcastPlay called with name :",
+		args.Name,
+		"index :",
+		args.Index,
+	)
+	err := p.PlayPodcast(args.Name, args.Index)
+	*reply = 1
+	logger.LogInfo("RPCPodcastPlay done")
+	return err
+}
+
+func (p *Player) RPCRemovePodcast(name string, reply *int) error {
+	logger.LogInfo("RPCRemovePodcast called with name :", name)
+	err := p.RemovePodcast(name)
+	*reply = 1
+	logger.LogInfo("RPCRemovePodcast done")
+	return err
+}
+
+func (p *Player) RPCRefreshPodcast(name string, reply *int) error {
+	logger.LogInfo("RPCRefreshPodcast called with name :", name)
+	err := p.RefreshPodcast(name)
+	*reply = 1
+	logger.LogInfo("RPCRefreshPodcast done")
+	return err
+}
+
+func (p *Player) RPCAddBookmark(name string, reply *int) error {
+	logger.LogInfo("RPCAddBookmark called with name :", name)
+	err := p.AddBookmark(name)
+	*reply = 1
+	logger.LogInfo("RPCAddBookmark done")
+	return err
+}
+
+func (p *Player) RPCRemoveBookmark(name string, reply *int) error {
+	logger.LogInfo("RPCRemoveBookmark called with name :", name)
+	err := p.RemoveBookmark(name)
+	*reply = 1
+	logger.LogInfo("RPCRemoveBookmark done")
+	return err
+}
+
+func (p *Player) RPCBookmarks(all bool, reply *[]shared.BookmarkInfo) error {
+	logger.LogInfo("RPCBookmarks called with all :", all)
+	var err error
+	*reply, err = p.Bookmarks(all)
+	logger.LogInfo("RPCBookmarks done with reply :", *reply)
+	return err
+}
+
+func (p *Player) RPCGotoBookmark(name string, reply *int) error {
+	logger.LogInfo("RPCGotoBookmark called with name :", name)
+	err := p.GotoBookmark(name)
+	*reply = 1
+	logger.LogInfo("RPCGotoBookmark done")
+	return err
+}
+
 func StartIPCServer(port string) {
 
 	// check update
@@ -243,6 +619,9 @@ func StartIPCServer(port string) {
 		return
 	}
 	logger.LogInfo("Player instance created and registered to RPC")
+	go player.runCacheEvictor()
+	go player.runPodcastRefresher()
+	go player.runResumeSaver()
 	lis, err := net.Listen("tcp", ":"+port)
 
 	logger.LogInfo("Starting IPC server on ", lis.Addr().String())
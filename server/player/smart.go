@@ -0,0 +1,122 @@
+package player
+
+import (
+	"github.com/Malwarize/retro/logger"
+	"github.com/Malwarize/retro/server/player/db"
+	"github.com/Malwarize/retro/shared"
+)
+
+// ##########################
+// # Smart playlist methods #
+// ##########################
+// playlistMusics returns the musics of the playlist, smart playlists are evaluated now
+func (p *Player) playlistMusics(plname string) ([]db.Music, error) {
+	if pl, err := p.Director.Db.GetPlaylist(plname); err == nil {
+		ms, err := p.Director.Db.GetMusicsFromPlaylist(
+			pl.Name,
+		)
+		if err != nil {
+			return nil, logger.LogError(
+				logger.GError(
+					"Failed to get musics from playlist",
+					err,
+				),
+			)
+		}
+		return ms, nil
+	}
+	if _, err := p.Director.Db.GetSmartPlaylist(plname); err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Playlist does not exist",
+			),
+		)
+	}
+	ms, err := p.Director.Db.GetSmartPlaylistMusics(
+		plname,
+	)
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to evaluate smart playlist",
+				err,
+			),
+		)
+	}
+	return ms, nil
+}
+
+func (p *Player) CreateSmartPlayList(name, rule string) error {
+	if _, err := p.Director.Db.GetPlaylist(name); err == nil {
+		return logger.LogError(
+			logger.GError(
+				"A playlist named " + name + " already exists",
+			),
+		)
+	}
+	err := p.Director.Db.AddSmartPlaylist(
+		name,
+		rule,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to create smart playlist",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+func (p *Player) RemoveSmartPlayList(name string) error {
+	if _, err := p.Director.Db.GetSmartPlaylist(name); err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Smart playlist does not exist",
+			),
+		)
+	}
+	err := p.Director.Db.RemoveSmartPlaylist(
+		name,
+	)
+	if err != nil {
+		return logger.LogError(
+			logger.GError(
+				"Failed to remove smart playlist",
+				err,
+			),
+		)
+	}
+	return nil
+}
+
+// SmartPlayLists returns the smart playlists with the number of songs matching their rule
+func (p *Player) SmartPlayLists() ([]shared.SmartPlayList, error) {
+	lists, err := p.Director.Db.GetSmartPlaylists()
+	if err != nil {
+		return nil, logger.LogError(
+			logger.GError(
+				"Failed to get smart playlists",
+				err,
+			),
+		)
+	}
+	var res []shared.SmartPlayList
+	for _, list := range lists {
+		count, err := p.Director.Db.CountSmartPlaylistMusics(list.Name)
+		if err != nil {
+			logger.LogWarn(
+				"Failed to evaluate smart playlist",
+				list.Name,
+				err,
+			)
+		}
+		res = append(res, shared.SmartPlayList{
+			Name:  list.Name,
+			Rule:  list.Rule,
+			Songs: count,
+		})
+	}
+	return res, nil
+}
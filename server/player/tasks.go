@@ -26,3 +26,13 @@ func (p *Player) errorTask(target string, err error) {
 		p.Tasks[target] = task
 	}
 }
+
+func (p *Player) setTaskProgress(target string, percent float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	task, ok := p.Tasks[target]
+	if ok {
+		task.Progress = percent
+		p.Tasks[target] = task
+	}
+}
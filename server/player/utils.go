@@ -74,6 +74,29 @@ func hash(data []byte) string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
+// fillMeta returns dst with its empty fields taken from src
+func fillMeta(dst, src shared.MusicMeta) shared.MusicMeta {
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if dst.Artist == "" {
+		dst.Artist = src.Artist
+	}
+	if dst.Album == "" {
+		dst.Album = src.Album
+	}
+	if dst.Duration == 0 {
+		dst.Duration = src.Duration
+	}
+	if dst.TrackNumber == 0 {
+		dst.TrackNumber = src.TrackNumber
+	}
+	if dst.Year == 0 {
+		dst.Year = src.Year
+	}
+	return dst
+}
+
 type DResults string
 
 const (
@@ -84,6 +107,10 @@ const (
 	DPlaylist DResults = "playlist"
 	DYoutube  DResults = "youtube"
 	DCache    DResults = "cache"
+	// DRemotePlaylist is a playlist url of an engine
+	DRemotePlaylist DResults = "remote playlist"
+	// DLive is an endless stream like a radio
+	DLive DResults = "live"
 )
 
 func adjustDiscordRPC(state shared.PState, music string) {
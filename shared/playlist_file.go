@@ -0,0 +1,191 @@
+package shared
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlayListEntry is a song of a playlist file, the location is a file path or a url
+type PlayListEntry struct {
+	Location string
+	Hash     string // set when the song is in the cache
+	Meta     MusicMeta
+}
+
+// String formats the entry with its metadata, or its location if it has none
+func (e PlayListEntry) String() string {
+	if e.Meta.Title == "" {
+		return e.Location
+	}
+	return e.Meta.String()
+}
+
+type ImportPlayListArgs struct {
+	PlayListName string
+	Entries      []PlayListEntry
+}
+
+type UnresolvedEntry struct {
+	PlayListEntry
+	Reason string
+}
+
+type ImportPlayListReply struct {
+	Added      int
+	Unresolved []UnresolvedEntry
+}
+
+// PlayListFormats are the extensions of the playlist files retro reads and writes
+var PlayListFormats = []string{".m3u", ".m3u8", ".xspf", ".jspf"}
+
+func playListFormat(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, format := range PlayListFormats {
+		if ext == format {
+			return ext, nil
+		}
+	}
+	return "", fmt.Errorf(
+		"unsupported playlist format %q, expected one of %s",
+		ext,
+		strings.Join(PlayListFormats, ", "),
+	)
+}
+
+// ReadPlayListFile parses the playlist file, the relative paths are resolved
+// against the directory of the file
+func ReadPlayListFile(path string) ([]PlayListEntry, error) {
+	format, err := playListFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case ".xspf":
+		return ParseXSPF(f, dir)
+	case ".jspf":
+		return ParseJSPF(f, dir)
+	default:
+		return ParseM3U(f, dir)
+	}
+}
+
+// WritePlayListFile writes the entries in the format of the file extension
+func WritePlayListFile(path string, name string, entries []PlayListEntry) error {
+	format, err := playListFormat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch format {
+	case ".xspf":
+		err = WriteXSPF(f, name, entries)
+	case ".jspf":
+		err = WriteJSPF(f, name, entries)
+	default:
+		err = WriteM3U(f, name, entries)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// resolveLocation turns file urls into paths and makes the relative paths absolute
+func resolveLocation(location, dir string) string {
+	if u, err := url.Parse(location); err == nil && u.Scheme == "file" {
+		return u.Path
+	}
+	if strings.Contains(location, "://") || filepath.IsAbs(location) {
+		return location
+	}
+	return filepath.Join(dir, filepath.FromSlash(location))
+}
+
+// ParseM3U reads an M3U/M3U8 playlist, the #EXTINF lines give the duration
+// and the "Artist - Title" of the entry that follows them
+func ParseM3U(r io.Reader, dir string) ([]PlayListEntry, error) {
+	var entries []PlayListEntry
+	var meta MusicMeta
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(
+			strings.TrimPrefix(scanner.Text(), "\ufeff"),
+		)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			meta = parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+		case strings.HasPrefix(line, "#"):
+			// other directives and comments
+			continue
+		default:
+			entries = append(entries, PlayListEntry{
+				Location: resolveLocation(line, dir),
+				Meta:     meta,
+			})
+			meta = MusicMeta{}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parseExtInf parses "<seconds> [attributes],<Artist - Title>"
+func parseExtInf(info string) MusicMeta {
+	var meta MusicMeta
+	length, title, found := strings.Cut(info, ",")
+	if !found {
+		return meta
+	}
+	if fields := strings.Fields(length); len(fields) > 0 {
+		if seconds, err := strconv.Atoi(fields[0]); err == nil && seconds > 0 {
+			meta.Duration = time.Duration(seconds) * time.Second
+		}
+	}
+	title = strings.TrimSpace(title)
+	if artist, song, found := strings.Cut(title, " - "); found {
+		meta.Artist = strings.TrimSpace(artist)
+		title = strings.TrimSpace(song)
+	}
+	meta.Title = title
+	return meta
+}
+
+func WriteM3U(w io.Writer, name string, entries []PlayListEntry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	fmt.Fprintln(bw, "#PLAYLIST:"+name)
+	for _, entry := range entries {
+		seconds := -1
+		if entry.Meta.Duration > 0 {
+			seconds = int(entry.Meta.Duration.Seconds())
+		}
+		title := entry.Meta.Title
+		if entry.Meta.Artist != "" {
+			title = entry.Meta.Artist + " - " + title
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", seconds, title)
+		fmt.Fprintln(bw, entry.Location)
+	}
+	return bw.Flush()
+}
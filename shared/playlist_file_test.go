@@ -0,0 +1,119 @@
+package shared
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseM3U(t *testing.T) {
+	dir := filepath.FromSlash("/playlists")
+	tests := []struct {
+		name string
+		data string
+		want []PlayListEntry
+	}{
+		{
+			name: "artist and title",
+			data: "#EXTM3U\n#EXTINF:369,Daft Punk - Get Lucky\n/music/get_lucky.mp3\n",
+			want: []PlayListEntry{
+				{
+					Location: "/music/get_lucky.mp3",
+					Meta:     MusicMeta{Title: "Get Lucky", Artist: "Daft Punk", Duration: 369 * time.Second},
+				},
+			},
+		},
+		{
+			name: "only the first dash splits",
+			data: "#EXTINF:200,Jay-Z - Song - Remix\nhttp://example.com/song.mp3\n",
+			want: []PlayListEntry{
+				{
+					Location: "http://example.com/song.mp3",
+					Meta:     MusicMeta{Title: "Song - Remix", Artist: "Jay-Z", Duration: 200 * time.Second},
+				},
+			},
+		},
+		{
+			name: "title without dash",
+			data: "#EXTINF:-1,Nightcall\n/music/nightcall.mp3\n#EXTINF:10,Jay-Z\n/music/jay-z.mp3\n",
+			want: []PlayListEntry{
+				{Location: "/music/nightcall.mp3", Meta: MusicMeta{Title: "Nightcall"}},
+				{Location: "/music/jay-z.mp3", Meta: MusicMeta{Title: "Jay-Z", Duration: 10 * time.Second}},
+			},
+		},
+		{
+			name: "attributes",
+			data: `#EXTINF:120 tvg-id="1" group-title="Rock",Queen - Bohemian Rhapsody` + "\n/music/queen.mp3\n",
+			want: []PlayListEntry{
+				{
+					Location: "/music/queen.mp3",
+					Meta:     MusicMeta{Title: "Bohemian Rhapsody", Artist: "Queen", Duration: 120 * time.Second},
+				},
+			},
+		},
+		{
+			name: "comments and blank lines",
+			data: "\ufeff#EXTM3U\n\n# a comment\n#PLAYLIST:mix\n   \n#EXTINF:5,A - B\n\n# between\n/music/b.mp3\n\n/music/c.mp3\r\n",
+			want: []PlayListEntry{
+				{Location: "/music/b.mp3", Meta: MusicMeta{Title: "B", Artist: "A", Duration: 5 * time.Second}},
+				{Location: "/music/c.mp3"},
+			},
+		},
+		{
+			name: "extinf without comma",
+			data: "#EXTINF:5\n/music/a.mp3\n",
+			want: []PlayListEntry{
+				{Location: "/music/a.mp3"},
+			},
+		},
+		{
+			name: "relative paths",
+			data: "songs/a.mp3\n../b.mp3\n  c d.mp3  \nfile:///music/e.mp3\n",
+			want: []PlayListEntry{
+				{Location: filepath.Join(dir, "songs", "a.mp3")},
+				{Location: filepath.Join(filepath.Dir(dir), "b.mp3")},
+				{Location: filepath.Join(dir, "c d.mp3")},
+				{Location: "/music/e.mp3"},
+			},
+		},
+		{
+			name: "empty",
+			data: "#EXTM3U\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := ParseM3U(strings.NewReader(test.data), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.want) {
+				t.Errorf("got %+v, want %+v", entries, test.want)
+			}
+		})
+	}
+}
+
+func TestM3URoundTrip(t *testing.T) {
+	want := []PlayListEntry{
+		{
+			Location: "/music/get_lucky.mp3",
+			Meta:     MusicMeta{Title: "Get Lucky", Artist: "Daft Punk", Duration: 369 * time.Second},
+		},
+		{Location: "https://example.com/nightcall.mp3", Meta: MusicMeta{Title: "Nightcall"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteM3U(&buf, "mix", want); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ParseM3U(&buf, "/elsewhere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+}
@@ -10,14 +10,75 @@ import (
 )
 
 type Task struct {
-	Type  int // download, search
-	Error string
+	Type     int // download, search
+	Error    string
+	Progress float64 // percent of a download
+}
+
+type DownloadState int
+
+const (
+	DownloadQueued DownloadState = iota
+	DownloadRunning
+	DownloadFailed
+	DownloadCanceled
+)
+
+func (s DownloadState) String() string {
+	switch s {
+	case DownloadQueued:
+		return "queued"
+	case DownloadRunning:
+		return "downloading"
+	case DownloadFailed:
+		return "failed"
+	case DownloadCanceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// DownloadTask is a download of the download manager
+type DownloadTask struct {
+	Id      int
+	Target  string // url of the music
+	Engine  string
+	State   DownloadState
+	Percent float64
+	Speed   string
+	ETA     string
+	Error   string
+}
+
+// MusicMeta is the metadata read from the tags of a music file
+// or reported by the engine it was downloaded from
+type MusicMeta struct {
+	Title       string
+	Artist      string
+	Album       string
+	Duration    time.Duration
+	TrackNumber int
+	Year        int
+}
+
+// String formats the metadata as "Artist — Title (Album)"
+func (m MusicMeta) String() string {
+	str := m.Title
+	if m.Artist != "" {
+		str = m.Artist + " — " + str
+	}
+	if m.Album != "" {
+		str += " (" + m.Album + ")"
+	}
+	return str
 }
 
 type Status struct {
 	CurrMusicIndex    int
 	CurrMusicPosition time.Duration
 	CurrMusicDuration time.Duration
+	CurrMusicMeta     MusicMeta
+	CurrMusicLive     bool // the current music is a live stream without duration
 	PlayerState       PState
 	MusicQueue        []string
 	Volume            uint8
@@ -29,6 +90,9 @@ func (s Status) String() string {
 	str += "CurrMusicIndex: " + fmt.Sprintf("%d", s.CurrMusicIndex) + "\n"
 	str += "CurrMusicPosition: " + s.CurrMusicPosition.String() + "\n"
 	str += "CurrMusicLength: " + s.CurrMusicDuration.String() + "\n"
+	if s.CurrMusicLive {
+		str += "CurrMusicLive: true\n"
+	}
 	switch s.PlayerState {
 	case Playing:
 		str += "PlayerState: Playing\n"
@@ -59,11 +123,129 @@ type SearchResult struct {
 	Destination string
 	Type        string
 	Duration    time.Duration
+	Artist      string
+	Album       string
+}
+
+// SearchArgs pages the search of a query, each source gives at most
+// Limit results after its Offset first ones
+type SearchArgs struct {
+	Query  string
+	Local  bool   // search the library only
+	Source string // search this source only, an engine or "cache" for the library
+	Offset int
+	Limit  int // zero for the max results of the sources
+}
+
+// DetectArgs is the query of DetectAndPlay, Source, Offset and Limit
+// page its search when it isn't detected
+type DetectArgs struct {
+	Query  string
+	Source string
+	Offset int
+	Limit  int
+}
+
+// DetectReply tells if the detected query was played, or its search results
+type DetectReply struct {
+	Searched bool // the query wasn't detected
+	Results  []SearchResult
+}
+
+// LibraryArgs filters, sorts and pages the library listings
+type LibraryArgs struct {
+	Artist string
+	Album  string
+	Source string
+	Sort   string // name, artist, album, year, duration, tracks, albums
+	Desc   bool
+	Offset int
+	Limit  int // 0 means no limit
+}
+
+type LibraryArtist struct {
+	Name   string
+	Albums int
+	Tracks int
+}
+
+type LibraryAlbum struct {
+	Name     string
+	Artist   string
+	Year     int
+	Tracks   int
+	Duration time.Duration
+}
+
+type LibraryTrack struct {
+	Name   string
+	Hash   string
+	Source string
+	Meta   MusicMeta
 }
 
 type AddToPlayListArgs struct {
 	PlayListName string
 	Query        string
+	Position     int // index to insert at, negative appends
+	// page the search of the query when it isn't detected
+	Source string
+	Offset int
+	Limit  int
+}
+
+// SelectArgs are the musics selected in a search, they are added to the
+// playlist at Position or to the queue when PlayListName is empty
+type SelectArgs struct {
+	Queries      []string
+	PlayListName string
+	Position     int // index to insert at, negative appends
+}
+
+type PlayListInfo struct {
+	Name        string
+	Description string
+	Songs       int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SmartPlayList is a playlist of the songs matching a rule, evaluated when played
+type SmartPlayList struct {
+	Name  string
+	Rule  string
+	Songs int
+}
+
+type SmartPlayListArgs struct {
+	Name string
+	Rule string
+}
+
+type RenamePlayListArgs struct {
+	PlayListName string
+	NewName      string
+}
+
+type CopyPlayListArgs struct {
+	Source      string
+	Destination string
+}
+
+type MergePlayListsArgs struct {
+	PlayListNames []string
+	Into          string
+}
+
+type DescribePlayListArgs struct {
+	PlayListName string
+	Description  string
+}
+
+type MoveMusicInPlayListArgs struct {
+	PlayListName string
+	From         int
+	To           int
 }
 
 type RemoveMusicFromPlayListArgs struct {
@@ -76,6 +258,54 @@ type PlayListPlayMusicArgs struct {
 	IndexOrName  IntOrString
 }
 
+// RemotePlaylist is a playlist on the server of an engine,
+// its url can be added to a playlist or played
+type RemotePlaylist struct {
+	Name   string
+	Url    string
+	Engine string
+	Songs  int
+}
+
+// PodcastInfo is a podcast feed the server follows
+type PodcastInfo struct {
+	Name        string
+	Url         string
+	Title       string
+	Author      string
+	Description string
+	Episodes    int
+	RefreshedAt time.Time
+}
+
+// EpisodeInfo is an episode of a podcast, the newest has the index 0
+type EpisodeInfo struct {
+	Index     int
+	Title     string
+	Url       string
+	Published time.Time
+	Duration  time.Duration
+	Cached    bool
+}
+
+type AddPodcastArgs struct {
+	Url  string
+	Name string // the title of the feed when empty
+}
+
+type PodcastPlayArgs struct {
+	Name  string
+	Index int
+}
+
+// BookmarkInfo is a named position in a music
+type BookmarkInfo struct {
+	Music    string
+	Name     string
+	Position time.Duration
+	Current  bool // the music is the one playing
+}
+
 // helper function to get mp3 duration
 func GetMp3Duration(path string) (time.Duration, error) {
 	f, err := os.Open(path)
@@ -124,7 +354,54 @@ type IntOrString struct {
 	IsInt  bool
 }
 
+type CacheUsage struct {
+	Total     int64            // size of all the musics in bytes
+	Evictable int64            // size of the musics that are not in a playlist
+	Sources   map[string]int64 // size per source
+	Count     int
+	MaxSize   int64
+	MaxAge    time.Duration
+}
+
+// FormatSize formats a size in bytes to a human readable string
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 type NameHash struct {
+	Name   string
+	Hash   string
+	Pinned bool
+}
+
+type PinArgs struct {
+	Target string // hash prefix or name
+	Pin    bool
+}
+
+// MusicFile is a cached music exported with its data
+type MusicFile struct {
 	Name string
-	Hash string
+	Data []byte
+}
+
+// EngineInfo is the status of an engine, the capabilities are only known
+// for the available engines
+type EngineInfo struct {
+	Name         string
+	Enabled      bool
+	Available    bool
+	Error        string // why the engine is not available
+	Priority     int
+	MaxResults   int
+	Capabilities []string
 }
@@ -0,0 +1,152 @@
+package shared
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// xspfTrack is a track of both XSPF and JSPF playlists, the durations are in milliseconds
+type xspfTrack struct {
+	Location []string `xml:"location" json:"location,omitempty"`
+	Title    string   `xml:"title,omitempty" json:"title,omitempty"`
+	Creator  string   `xml:"creator,omitempty" json:"creator,omitempty"`
+	Album    string   `xml:"album,omitempty" json:"album,omitempty"`
+	Duration int64    `xml:"duration,omitempty" json:"duration,omitempty"`
+	TrackNum int      `xml:"trackNum,omitempty" json:"trackNum,omitempty"`
+}
+
+// xspfNamespace is written in the playlists, it isn't required in the ones read
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist" json:"-"`
+	Xmlns   string      `xml:"xmlns,attr" json:"-"`
+	Version string      `xml:"version,attr" json:"-"`
+	Title   string      `xml:"title,omitempty" json:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track" json:"track"`
+}
+
+type jspfDocument struct {
+	Playlist xspfPlaylist `json:"playlist"`
+}
+
+// xspfLocation turns the location uri of a track into a path or a url
+func xspfLocation(location, dir string) string {
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return ""
+	}
+	if !strings.Contains(location, "://") {
+		// relative uri
+		if path, err := url.PathUnescape(location); err == nil {
+			location = path
+		}
+	}
+	return resolveLocation(location, dir)
+}
+
+// xspfURI turns a path into the uri written in the location of a track
+func xspfURI(location string) string {
+	switch {
+	case strings.Contains(location, "://"):
+		return location
+	case filepath.IsAbs(location):
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(location)}).String()
+	default:
+		return (&url.URL{Path: filepath.ToSlash(location)}).String()
+	}
+}
+
+func (t xspfTrack) entry(dir string) PlayListEntry {
+	var location string
+	if len(t.Location) > 0 {
+		location = xspfLocation(t.Location[0], dir)
+	}
+	return PlayListEntry{
+		Location: location,
+		Meta: MusicMeta{
+			Title:       strings.TrimSpace(t.Title),
+			Artist:      strings.TrimSpace(t.Creator),
+			Album:       strings.TrimSpace(t.Album),
+			Duration:    time.Duration(t.Duration) * time.Millisecond,
+			TrackNumber: t.TrackNum,
+		},
+	}
+}
+
+func newXspfPlaylist(name string, entries []PlayListEntry) xspfPlaylist {
+	playlist := xspfPlaylist{
+		Xmlns:   xspfNamespace,
+		Version: "1",
+		Title:   name,
+		Tracks:  []xspfTrack{},
+	}
+	for _, entry := range entries {
+		track := xspfTrack{
+			Title:    entry.Meta.Title,
+			Creator:  entry.Meta.Artist,
+			Album:    entry.Meta.Album,
+			Duration: entry.Meta.Duration.Milliseconds(),
+			TrackNum: entry.Meta.TrackNumber,
+		}
+		if entry.Location != "" {
+			track.Location = []string{xspfURI(entry.Location)}
+		}
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+	return playlist
+}
+
+// ParseXSPF reads an XSPF playlist, the entries without location
+// are kept so they can be matched by their metadata
+func ParseXSPF(r io.Reader, dir string) ([]PlayListEntry, error) {
+	var playlist xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, err
+	}
+	var entries []PlayListEntry
+	for _, track := range playlist.Tracks {
+		entries = append(entries, track.entry(dir))
+	}
+	return entries, nil
+}
+
+func WriteXSPF(w io.Writer, name string, entries []PlayListEntry) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(newXspfPlaylist(name, entries)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ParseJSPF reads a JSPF playlist, the JSON form of XSPF used by ListenBrainz
+func ParseJSPF(r io.Reader, dir string) ([]PlayListEntry, error) {
+	var document jspfDocument
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+	var entries []PlayListEntry
+	for _, track := range document.Playlist.Tracks {
+		entries = append(entries, track.entry(dir))
+	}
+	return entries, nil
+}
+
+func WriteJSPF(w io.Writer, name string, entries []PlayListEntry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jspfDocument{
+		Playlist: newXspfPlaylist(name, entries),
+	})
+}
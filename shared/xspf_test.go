@@ -0,0 +1,187 @@
+package shared
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var xspfEntries = []PlayListEntry{
+	{
+		Location: "/music/Daft Punk/Get Lucky.mp3",
+		Meta: MusicMeta{
+			Title:       "Get Lucky",
+			Artist:      "Daft Punk",
+			Album:       "Random Access Memories",
+			Duration:    369 * time.Second,
+			TrackNumber: 8,
+		},
+	},
+	{
+		Location: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1",
+		Meta: MusicMeta{
+			Title:    "Never Gonna Give You Up",
+			Artist:   "Rick Astley",
+			Duration: 213500 * time.Millisecond,
+		},
+	},
+	{
+		// kept so it can be matched by its metadata
+		Meta: MusicMeta{
+			Title:  "Nightcall",
+			Artist: "Kavinsky",
+		},
+	},
+}
+
+func TestXSPFRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXSPF(&buf, "mix", xspfEntries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<playlist xmlns="http://xspf.org/ns/0/" version="1">`) {
+		t.Errorf("the playlist has no xspf namespace:\n%s", buf.String())
+	}
+	entries, err := ParseXSPF(&buf, "/elsewhere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, xspfEntries) {
+		t.Errorf("got %+v, want %+v", entries, xspfEntries)
+	}
+}
+
+func TestJSPFRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSPF(&buf, "mix", xspfEntries); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ParseJSPF(&buf, "/elsewhere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, xspfEntries) {
+		t.Errorf("got %+v, want %+v", entries, xspfEntries)
+	}
+}
+
+func TestParseXSPF(t *testing.T) {
+	dir := filepath.FromSlash("/playlists")
+	tests := []struct {
+		name string
+		data string
+		want []PlayListEntry
+	}{
+		{
+			name: "namespace",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track><location>file:///music/a%20b.mp3</location><title>A B</title></track>
+  </trackList>
+</playlist>`,
+			want: []PlayListEntry{
+				{Location: "/music/a b.mp3", Meta: MusicMeta{Title: "A B"}},
+			},
+		},
+		{
+			name: "no namespace",
+			data: `<playlist version="1">
+  <trackList>
+    <track><location>songs/a%20b.mp3</location><duration>61000</duration></track>
+  </trackList>
+</playlist>`,
+			want: []PlayListEntry{
+				{Location: filepath.Join(dir, "songs", "a b.mp3"), Meta: MusicMeta{Duration: 61 * time.Second}},
+			},
+		},
+		{
+			name: "several locations",
+			data: `<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track>
+      <location> http://example.com/first.mp3 </location>
+      <location>http://example.com/second.mp3</location>
+      <creator> Kavinsky </creator>
+      <trackNum>3</trackNum>
+    </track>
+  </trackList>
+</playlist>`,
+			want: []PlayListEntry{
+				{Location: "http://example.com/first.mp3", Meta: MusicMeta{Artist: "Kavinsky", TrackNumber: 3}},
+			},
+		},
+		{
+			name: "empty",
+			data: `<playlist version="1" xmlns="http://xspf.org/ns/0/"><trackList/></playlist>`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := ParseXSPF(strings.NewReader(test.data), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.want) {
+				t.Errorf("got %+v, want %+v", entries, test.want)
+			}
+		})
+	}
+
+	for _, data := range []string{"", "<html><body/></html>", "<playlist><trackList>"} {
+		if _, err := ParseXSPF(strings.NewReader(data), dir); err == nil {
+			t.Errorf("ParseXSPF(%q) succeeded", data)
+		}
+	}
+}
+
+func TestParseJSPF(t *testing.T) {
+	// as exported by ListenBrainz
+	data := `{
+  "playlist": {
+    "title": "Weekly Jams",
+    "creator": "listenbrainz",
+    "track": [
+      {
+        "title": "Get Lucky",
+        "creator": "Daft Punk",
+        "album": "Random Access Memories",
+        "duration": 369000,
+        "identifier": ["https://musicbrainz.org/recording/1"]
+      },
+      {
+        "title": "Nightcall",
+        "location": ["https://example.com/nightcall.mp3"]
+      }
+    ]
+  }
+}`
+	entries, err := ParseJSPF(strings.NewReader(data), "/playlists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PlayListEntry{
+		{
+			Meta: MusicMeta{
+				Title:    "Get Lucky",
+				Artist:   "Daft Punk",
+				Album:    "Random Access Memories",
+				Duration: 369 * time.Second,
+			},
+		},
+		{
+			Location: "https://example.com/nightcall.mp3",
+			Meta:     MusicMeta{Title: "Nightcall"},
+		},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+
+	if _, err := ParseJSPF(strings.NewReader("{"), "/playlists"); err == nil {
+		t.Error("ParseJSPF of an invalid document succeeded")
+	}
+}